@@ -0,0 +1,185 @@
+package arith
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"playground/go/bitbuffer"
+)
+
+// Operation is one coded item of a random round trip.
+type Operation struct {
+	Kind   int // 0 context bit, 1 bypass bits, 2 model symbol
+	Index  int // Context or model index
+	Width  uint8
+	Value  uint64
+	Symbol int
+}
+
+// RandomOperations generates count operations over skewed sources, so the adaptive models see
+// both predictable and unpredictable data.
+func RandomOperations(rng *rand.Rand, count int, contexts int, sizes []int) []Operation {
+	var (
+		operations = make([]Operation, count)
+		bias       = make([]float64, contexts)
+	)
+	for i := range bias {
+		bias[i] = rng.Float64()
+	}
+	for i := range operations {
+		op := Operation{Kind: rng.Intn(3)}
+		switch op.Kind {
+		case 0:
+			op.Index = rng.Intn(contexts)
+			if rng.Float64() < bias[op.Index] {
+				op.Value = 1
+			}
+		case 1:
+			op.Width = uint8(rng.Intn(64) + 1)
+			op.Value = rng.Uint64() >> (64 - op.Width)
+		case 2:
+			op.Index = rng.Intn(len(sizes))
+			// Square the uniform draw to favour small symbols
+			f := rng.Float64()
+			op.Symbol = int(f * f * float64(sizes[op.Index]))
+		}
+		operations[i] = op
+	}
+	return operations
+}
+
+func TestRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	sizes := []int{2, 3, 16, 256, MaxTotal / increment}
+	for trial := range 50 {
+		order := bitbuffer.MSBFirst
+		if trial%2 == 1 {
+			order = bitbuffer.LSBFirst
+		}
+		operations := RandomOperations(rng, rng.Intn(2000)*(trial%10), 8, sizes)
+
+		writer := bitbuffer.CreateWriter(bitbuffer.WithBitOrder(order))
+		writer.Write(5, 0x15)
+		encoder := CreateEncoder(writer)
+		contexts := make([]Context, 8)
+		models := make([]*Model, len(sizes))
+		for i, size := range sizes {
+			models[i], _ = CreateModel(size)
+		}
+		for i, op := range operations {
+			var err error
+			switch op.Kind {
+			case 0:
+				err = encoder.EncodeBit(&contexts[op.Index], op.Value)
+			case 1:
+				err = encoder.EncodeBypass(op.Width, op.Value)
+			case 2:
+				err = encoder.Encode(models[op.Index], op.Symbol)
+			}
+			if err != nil {
+				t.Fatalf("trial %d, operation %d: encode error: %v", trial, i, err)
+			}
+		}
+		if err := encoder.Finish(); err != nil {
+			t.Fatalf("finish error: %v", err)
+		}
+		// A field after the coded data must read back from the same position
+		writer.Write(11, 0x5A5)
+
+		reader := bitbuffer.CreateReader(writer.Buff[:(writer.NumWritten()+7)/8], bitbuffer.WithBitOrder(order))
+		if value, _ := reader.Read(5); value != 0x15 {
+			t.Fatalf("leading field mismatch: got %x", value)
+		}
+		decoder := CreateDecoder(reader)
+		contexts = make([]Context, 8)
+		for i, size := range sizes {
+			models[i], _ = CreateModel(size)
+		}
+		for i, op := range operations {
+			var (
+				value  uint64
+				symbol int
+				err    error
+			)
+			switch op.Kind {
+			case 0:
+				value, err = decoder.DecodeBit(&contexts[op.Index])
+			case 1:
+				value, err = decoder.DecodeBypass(op.Width)
+			case 2:
+				symbol, err = decoder.Decode(models[op.Index])
+			}
+			if err != nil || value != op.Value || symbol != op.Symbol {
+				t.Fatalf("trial %d, operation %d: mismatch: wrote %d/%d, read %d/%d, err: %v", trial, i, op.Value, op.Symbol, value, symbol, err)
+			}
+		}
+		if err := decoder.Finish(); err != nil {
+			t.Fatalf("trial %d: finish error: %v", trial, err)
+		}
+		if value, err := reader.Read(11); err != nil || value != 0x5A5 {
+			t.Errorf("trial %d: trailing field mismatch: got %x, err: %v", trial, value, err)
+		}
+		if reader.NumRead() != writer.NumWritten() {
+			t.Errorf("trial %d: unexpected bits read: got %d, want %d", trial, reader.NumRead(), writer.NumWritten())
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	const count = 10000
+
+	// A bit that is one 5% of the time carries about 0.29 bits of information
+	var (
+		writer  = bitbuffer.CreateWriter()
+		encoder = CreateEncoder(writer)
+		ctx     Context
+	)
+	for range count {
+		var bit uint64
+		if rng.Intn(20) == 0 {
+			bit = 1
+		}
+		encoder.EncodeBit(&ctx, bit)
+	}
+	encoder.Finish()
+	if writer.NumWritten() > count*4/10 {
+		t.Errorf("skewed bits: %d bits for %d decisions", writer.NumWritten(), count)
+	}
+
+	// Symbols drawn from 4 of 256 values carry 2 bits of information each
+	writer = bitbuffer.CreateWriter()
+	encoder = CreateEncoder(writer)
+	model, _ := CreateModel(256)
+	for range count {
+		encoder.Encode(model, 'a'+rng.Intn(4))
+	}
+	encoder.Finish()
+	if writer.NumWritten() > count*25/10 {
+		t.Errorf("skewed symbols: %d bits for %d symbols", writer.NumWritten(), count)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := CreateModel(0); !errors.Is(err, ErrInvalidAlphabet) {
+		t.Errorf("expected ErrInvalidAlphabet, got %v", err)
+	}
+	if _, err := CreateModel(MaxTotal); !errors.Is(err, ErrInvalidAlphabet) {
+		t.Errorf("expected ErrInvalidAlphabet, got %v", err)
+	}
+	model, _ := CreateModel(4)
+	encoder := CreateEncoder(bitbuffer.CreateWriter())
+	if err := encoder.Encode(model, 4); !errors.Is(err, ErrInvalidSymbol) {
+		t.Errorf("expected ErrInvalidSymbol, got %v", err)
+	}
+	encoder.Finish()
+	if err := encoder.Encode(model, 1); !errors.Is(err, ErrFinished) {
+		t.Errorf("expected ErrFinished, got %v", err)
+	}
+	decoder := CreateDecoder(bitbuffer.CreateReader([]byte{0xFF}))
+	if _, err := decoder.Decode(model); !errors.Is(err, bitbuffer.ErrNotEnoughBits) {
+		t.Errorf("expected ErrNotEnoughBits, got %v", err)
+	}
+}
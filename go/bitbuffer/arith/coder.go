@@ -0,0 +1,193 @@
+// Package arith implements adaptive arithmetic coding over bitbuffer.Codec: a context-adaptive
+// binary coder in the spirit of CABAC and a multi-symbol range coder driven by adaptive frequency
+// models.
+//
+// Both share one bit-oriented coder with 32 bits of precision. Encoder and Decoder both end with
+// Finish, after which the Decoder has consumed exactly the bits the Encoder wrote, so other fields
+// may follow the coded data on the same Codec.
+package arith
+
+import (
+	"errors"
+	"math/bits"
+
+	"playground/go/bitbuffer"
+)
+
+const (
+	precision = 32
+	whole     = uint64(1) << precision
+	half      = whole / 2
+	quarter   = whole / 4
+
+	// MaxTotal is the largest total frequency a model may have, keeping every interval non-empty.
+	MaxTotal = 1 << 16
+)
+
+var (
+	// ErrFinished reports a write to an Encoder after Finish.
+	ErrFinished = errors.New("arithmetic encoder is finished")
+	// ErrInvalidAlphabet reports a model alphabet size outside 1 to MaxTotal/32.
+	ErrInvalidAlphabet = errors.New("invalid model alphabet size")
+	// ErrInvalidSymbol reports a symbol outside the alphabet of a model.
+	ErrInvalidSymbol = errors.New("symbol outside model alphabet")
+	// ErrCorrupt reports input that does not decode to any symbol.
+	ErrCorrupt = errors.New("corrupt arithmetic coded data")
+)
+
+// Encoder writes arithmetic coded symbols to a bit stream.
+type Encoder struct {
+	codec    *bitbuffer.Codec
+	low      uint64
+	high     uint64
+	pending  uint64 // Bits whose value waits on the next resolved bit
+	finished bool
+}
+
+// CreateEncoder creates a new Encoder writing to codec.
+func CreateEncoder(codec *bitbuffer.Codec) *Encoder {
+	return &Encoder{codec: codec, high: whole - 1}
+}
+
+// Codec returns the underlying bit stream.
+func (e *Encoder) Codec() *bitbuffer.Codec {
+	return e.codec
+}
+
+// emit writes a resolved bit followed by the pending bits, which take the opposite value.
+func (e *Encoder) emit(bit uint64) error {
+	if err := e.codec.Write(1, bit); err != nil {
+		return err
+	}
+	for ; e.pending > 0; e.pending-- {
+		if err := e.codec.Write(1, bit^1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode narrows the interval to [start, end) out of total and writes the bits it resolves.
+func (e *Encoder) encode(start, end, total uint64) error {
+	if e.finished {
+		return ErrFinished
+	}
+	span := e.high - e.low + 1
+	e.high = e.low + span*end/total - 1
+	e.low = e.low + span*start/total
+	for {
+		switch {
+		case e.high < half:
+			if err := e.emit(0); err != nil {
+				return err
+			}
+		case e.low >= half:
+			if err := e.emit(1); err != nil {
+				return err
+			}
+			e.low = e.low - half
+			e.high = e.high - half
+		case e.low >= quarter && e.high < half+quarter:
+			e.pending++
+			e.low = e.low - quarter
+			e.high = e.high - quarter
+		default:
+			return nil
+		}
+		e.low = e.low << 1
+		e.high = e.high<<1 | 1
+	}
+}
+
+// Finish writes the bits that identify the final interval. The Encoder must not be used afterwards.
+func (e *Encoder) Finish() error {
+	if e.finished {
+		return ErrFinished
+	}
+	e.finished = true
+	// Writing all of low keeps the decoder lookahead within the encoding
+	if err := e.emit(e.low >> (precision - 1)); err != nil {
+		return err
+	}
+	value := e.low & (half - 1)
+	if e.codec.Order() == bitbuffer.LSBFirst {
+		value = bits.Reverse64(value) >> (64 - precision + 1)
+	}
+	return e.codec.Write(precision-1, value)
+}
+
+// Decoder reads arithmetic coded symbols from a bit stream.
+type Decoder struct {
+	codec   *bitbuffer.Codec
+	low     uint64
+	high    uint64
+	value   uint64
+	started bool
+}
+
+// CreateDecoder creates a new Decoder reading from codec.
+func CreateDecoder(codec *bitbuffer.Codec) *Decoder {
+	return &Decoder{codec: codec, high: whole - 1}
+}
+
+// Codec returns the underlying bit stream.
+func (d *Decoder) Codec() *bitbuffer.Codec {
+	return d.codec
+}
+
+// target returns the position of the coded value scaled to total, reading the initial bits on
+// first use. Coded bits are significant first whatever the bit order of the Codec.
+func (d *Decoder) target(total uint64) (uint64, error) {
+	if !d.started {
+		value, err := d.codec.Read(precision)
+		if err != nil {
+			return 0, err
+		}
+		if d.codec.Order() == bitbuffer.LSBFirst {
+			value = bits.Reverse64(value) >> (64 - precision)
+		}
+		d.value = value
+		d.started = true
+	}
+	span := d.high - d.low + 1
+	return ((d.value-d.low+1)*total - 1) / span, nil
+}
+
+// Finish consumes the rest of the encoding, leaving the Codec positioned after it even when no
+// symbol was decoded.
+func (d *Decoder) Finish() error {
+	if d.started {
+		return nil
+	}
+	_, err := d.target(1)
+	return err
+}
+
+// decode narrows the interval to the [start, end) found for the target, mirroring Encoder.encode.
+func (d *Decoder) decode(start, end, total uint64) error {
+	span := d.high - d.low + 1
+	d.high = d.low + span*end/total - 1
+	d.low = d.low + span*start/total
+	for {
+		switch {
+		case d.high < half:
+		case d.low >= half:
+			d.low = d.low - half
+			d.high = d.high - half
+			d.value = d.value - half
+		case d.low >= quarter && d.high < half+quarter:
+			d.low = d.low - quarter
+			d.high = d.high - quarter
+			d.value = d.value - quarter
+		default:
+			return nil
+		}
+		bit, err := d.codec.Read(1)
+		if err != nil {
+			return err
+		}
+		d.low = d.low << 1
+		d.high = d.high<<1 | 1
+		d.value = d.value<<1 | bit
+	}
+}
@@ -0,0 +1,190 @@
+package arith
+
+const (
+	probabilityBits = 12
+	probabilityOne  = 1 << probabilityBits
+	adaptShift      = 5
+
+	// increment is the frequency added to a symbol each time it is coded.
+	increment = 32
+)
+
+// Context is an adaptive estimate of the probability that a binary decision is zero, as kept per
+// syntax element by CABAC. The zero value is ready to use and starts at one half.
+type Context struct {
+	zero uint16 // Probability of zero scaled by 1<<probabilityBits, zero meaning unused
+}
+
+// probability returns the current estimate, initialising an unused context.
+func (c *Context) probability() uint64 {
+	if c.zero == 0 {
+		c.zero = probabilityOne / 2
+	}
+	return uint64(c.zero)
+}
+
+// update moves the estimate a fixed fraction towards the coded bit.
+func (c *Context) update(bit uint64) {
+	if bit == 0 {
+		c.zero = c.zero + (probabilityOne-c.zero)>>adaptShift
+	} else {
+		c.zero = c.zero - c.zero>>adaptShift
+	}
+}
+
+// EncodeBit encodes the least significant bit of bit with the probability held by ctx, then adapts ctx.
+func (e *Encoder) EncodeBit(ctx *Context, bit uint64) error {
+	var (
+		zero = ctx.probability()
+		err  error
+	)
+	bit = bit & 1
+	if bit == 0 {
+		err = e.encode(0, zero, probabilityOne)
+	} else {
+		err = e.encode(zero, probabilityOne, probabilityOne)
+	}
+	if err != nil {
+		return err
+	}
+	ctx.update(bit)
+	return nil
+}
+
+// EncodeBypass encodes num bits of value, most significant first, with a fixed probability of one half.
+func (e *Encoder) EncodeBypass(num uint8, value uint64) error {
+	for i := int(num) - 1; i >= 0; i-- {
+		bit := value >> uint(i) & 1
+		if err := e.encode(bit, bit+1, 2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeBit decodes a bit with the probability held by ctx, then adapts ctx.
+func (d *Decoder) DecodeBit(ctx *Context) (uint64, error) {
+	zero := ctx.probability()
+	target, err := d.target(probabilityOne)
+	if err != nil {
+		return 0, err
+	}
+	var bit uint64
+	if target < zero {
+		err = d.decode(0, zero, probabilityOne)
+	} else {
+		bit = 1
+		err = d.decode(zero, probabilityOne, probabilityOne)
+	}
+	if err != nil {
+		return 0, err
+	}
+	ctx.update(bit)
+	return bit, nil
+}
+
+// DecodeBypass decodes num bits encoded with EncodeBypass.
+func (d *Decoder) DecodeBypass(num uint8) (uint64, error) {
+	var value uint64
+	for range num {
+		bit, err := d.target(2)
+		if err != nil {
+			return 0, err
+		}
+		if err := d.decode(bit, bit+1, 2); err != nil {
+			return 0, err
+		}
+		value = value<<1 | bit
+	}
+	return value, nil
+}
+
+// Model is an adaptive frequency model over the symbols 0 to n-1. Every symbol starts with a
+// frequency of one, and coding a symbol raises its frequency, halving all frequencies when the
+// total would exceed MaxTotal.
+type Model struct {
+	frequencies []uint64
+	total       uint64
+}
+
+// CreateModel creates a new Model for an alphabet of n symbols, n between 1 and MaxTotal/increment.
+func CreateModel(n int) (*Model, error) {
+	if n < 1 || n > MaxTotal/increment {
+		return nil, ErrInvalidAlphabet
+	}
+	m := &Model{frequencies: make([]uint64, n), total: uint64(n)}
+	for symbol := range m.frequencies {
+		m.frequencies[symbol] = 1
+	}
+	return m, nil
+}
+
+// Len returns the size of the alphabet.
+func (m *Model) Len() int {
+	return len(m.frequencies)
+}
+
+// interval returns the cumulative frequency range of symbol.
+func (m *Model) interval(symbol int) (uint64, uint64) {
+	var start uint64
+	for _, frequency := range m.frequencies[:symbol] {
+		start = start + frequency
+	}
+	return start, start + m.frequencies[symbol]
+}
+
+// find returns the symbol whose cumulative frequency range contains target.
+func (m *Model) find(target uint64) (int, uint64, uint64, bool) {
+	var start uint64
+	for symbol, frequency := range m.frequencies {
+		if target < start+frequency {
+			return symbol, start, start + frequency, true
+		}
+		start = start + frequency
+	}
+	return 0, 0, 0, false
+}
+
+// update raises the frequency of symbol, rescaling the model when it grows too large.
+func (m *Model) update(symbol int) {
+	m.frequencies[symbol] = m.frequencies[symbol] + increment
+	m.total = m.total + increment
+	if m.total <= MaxTotal {
+		return
+	}
+	m.total = 0
+	for i, frequency := range m.frequencies {
+		m.frequencies[i] = (frequency + 1) / 2
+		m.total = m.total + m.frequencies[i]
+	}
+}
+
+// Encode encodes symbol with the frequencies held by m, then adapts m.
+func (e *Encoder) Encode(m *Model, symbol int) error {
+	if symbol < 0 || symbol >= len(m.frequencies) {
+		return ErrInvalidSymbol
+	}
+	start, end := m.interval(symbol)
+	if err := e.encode(start, end, m.total); err != nil {
+		return err
+	}
+	m.update(symbol)
+	return nil
+}
+
+// Decode decodes a symbol with the frequencies held by m, then adapts m.
+func (d *Decoder) Decode(m *Model) (int, error) {
+	target, err := d.target(m.total)
+	if err != nil {
+		return 0, err
+	}
+	symbol, start, end, ok := m.find(target)
+	if !ok {
+		return 0, ErrCorrupt
+	}
+	if err := d.decode(start, end, m.total); err != nil {
+		return 0, err
+	}
+	m.update(symbol)
+	return symbol, nil
+}
@@ -1,29 +1,128 @@
 package bitbuffer
 
 import (
+	"encoding/binary"
 	"errors"
+	"io"
+	"math"
 	"slices"
 )
 
-// InitialBufferSize is the initial capacity for the buffer in CreateWriter.
-var InitialBufferSize = 64
+// InitialBufferSize is the default buffer capacity of a Codec, see WithCapacity.
+const InitialBufferSize = 64
+
+// BitOrder selects how bits are packed within each byte.
+type BitOrder uint8
+
+const (
+	// MSBFirst packs bits starting at the most significant bit of each byte.
+	MSBFirst BitOrder = iota
+	// LSBFirst packs bits starting at the least significant bit of each byte, as used by DEFLATE.
+	LSBFirst
+)
+
+// Padding selects the bits used to fill up to a byte boundary.
+type Padding uint8
+
+const (
+	// PadZeros pads with zero bits.
+	PadZeros Padding = iota
+	// PadOnes pads with one bits.
+	PadOnes
+	// PadStuffing writes a one bit followed by zero bits, as in H.264 rbsp_trailing_bits.
+	// Unlike the other modes it always emits at least one bit, a full byte when already aligned.
+	PadStuffing
+)
 
 // Codec manages a bit stream for encoding and decoding.
 type Codec struct {
 	Buff        []byte
-	offset      uint8  // Bit position in the last byte (0–7)
-	bitsWritten uint64 // Number of bits written
-	bitsRead    uint64 // Number of bits read
+	order       BitOrder  // Bit packing order within each byte
+	offset      uint8     // Bit position in the last byte (0–7)
+	cursor      uint64    // Read position in bits relative to the start of Buff
+	base        uint64    // Bits discarded from the front of Buff by a streaming reader
+	bitsWritten uint64    // Number of bits written
+	bitsRead    uint64    // Number of bits read
+	src         io.Reader // Source for streaming reads, nil for in-memory
+	dst         io.Writer // Sink for streaming writes, nil for in-memory
+	trace       *Trace    // Recorded operations, nil unless tracing
+	capacity    int       // Initial buffer capacity, and flush or refill size when streaming
+	reserved    int       // Reservations not yet set, holding back streaming flushes
+}
+
+// Option is a functional option for configuring a Codec
+type Option func(*Codec)
+
+// WithBitOrder sets the bit packing order of the Codec
+func WithBitOrder(order BitOrder) Option {
+	return func(w *Codec) {
+		w.order = order
+	}
+}
+
+// WithCapacity sets the initial buffer capacity in bytes, InitialBufferSize by default. Streaming
+// codecs also use it as the amount of data to buffer before flushing or refilling.
+func WithCapacity(capacity int) Option {
+	return func(w *Codec) {
+		w.capacity = max(capacity, 1)
+	}
+}
+
+// configure applies the options to the Codec.
+func (w *Codec) configure(opts []Option) *Codec {
+	w.capacity = InitialBufferSize
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
 // CreateWriter creates a new Codec instance for writing with pre-allocated buffer capacity.
-func CreateWriter() *Codec {
-	return &Codec{Buff: make([]byte, 0, InitialBufferSize)}
+func CreateWriter(opts ...Option) *Codec {
+	w := (&Codec{}).configure(opts)
+	w.Buff = make([]byte, 0, w.capacity)
+	return w
 }
 
 // CreateReader creates a new Codec instance for reading from the given byte array.
-func CreateReader(data []byte) *Codec {
-	return &Codec{Buff: data}
+func CreateReader(data []byte, opts ...Option) *Codec {
+	w := &Codec{Buff: data}
+	return w.configure(opts)
+}
+
+// CreateStreamWriter creates a new Codec instance that flushes completed bytes to the given writer.
+// Call Close once done writing to emit the final partial byte.
+func CreateStreamWriter(dst io.Writer, opts ...Option) *Codec {
+	w := (&Codec{dst: dst}).configure(opts)
+	w.Buff = make([]byte, 0, w.capacity)
+	return w
+}
+
+// CreateStreamReader creates a new Codec instance that pulls bytes from the given reader on demand.
+func CreateStreamReader(src io.Reader, opts ...Option) *Codec {
+	w := (&Codec{src: src}).configure(opts)
+	w.Buff = make([]byte, 0, w.capacity)
+	return w
+}
+
+// Reset empties the Codec for reuse, clearing the bit counters and positions while keeping the
+// buffer capacity and options. A reader is left with no data.
+func (w *Codec) Reset() {
+	w.Buff = w.Buff[:0]
+	w.offset = 0
+	w.cursor = 0
+	w.base = 0
+	w.bitsWritten = 0
+	w.bitsRead = 0
+	w.reserved = 0
+	if w.trace != nil {
+		w.trace.Reset()
+	}
+}
+
+// Order returns the bit packing order of the Codec.
+func (w *Codec) Order() BitOrder {
+	return w.order
 }
 
 // Len returns the number of bytes in the buffer.
@@ -36,9 +135,9 @@ func (w *Codec) Cap() int {
 	return cap(w.Buff)
 }
 
-// available returns the number of bits available in the buffer.
+// available returns the number of bits available for reading in the buffer.
 func (w *Codec) available() int {
-	return 8*w.Len() - int(w.offset)
+	return 8*w.Len() - int(w.cursor)
 }
 
 // grow extends the buffer by n bytes.
@@ -47,61 +146,183 @@ func (w *Codec) grow(n int) {
 		w.Buff = slices.Grow(w.Buff, n+(2*w.Cap()))
 	}
 	w.Buff = w.Buff[:len(w.Buff)+n]
+	clear(w.Buff[len(w.Buff)-n:])
 }
 
-// advance moves to the next byte by removing the first byte.
-func (w *Codec) advance() error {
-	if w.Len() == 0 {
-		return errors.New("no more content")
+// fill reads from the source until at least num bits are buffered or the source is exhausted.
+func (w *Codec) fill(num int) error {
+	for w.available() < num {
+		if w.Len() == w.Cap() {
+			w.compact()
+		}
+		if w.Len() == w.Cap() {
+			w.Buff = slices.Grow(w.Buff, w.capacity)
+		}
+		n, err := w.src.Read(w.Buff[w.Len():w.Cap()])
+		w.Buff = w.Buff[:w.Len()+n]
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
-	w.Buff = w.Buff[1:]
-	w.offset = 0
 	return nil
 }
 
-// append adds a new byte to the buffer and resets offset.
-func (w *Codec) append() error {
-	w.grow(1)
-	w.offset = 0
-	return nil
+// compact discards fully consumed bytes from the front of a streaming reader's buffer.
+func (w *Codec) compact() {
+	drop := int(w.cursor / 8)
+	if drop == 0 {
+		return
+	}
+	n := copy(w.Buff, w.Buff[drop:])
+	w.Buff = w.Buff[:n]
+	w.base = w.base + uint64(8*drop)
+	w.cursor = w.cursor - uint64(8*drop)
+}
+
+// flushed returns the number of written bits a streaming writer has passed to its destination.
+func (w *Codec) flushed() uint64 {
+	if w.Len() == 0 {
+		return w.bitsWritten
+	}
+	return w.bitsWritten - 8*uint64(w.Len()-1) - uint64(w.offset)
 }
 
 // Write writes the least significant num bits of value to the bit stream.
 func (w *Codec) Write(num uint8, value uint64) error {
 	if num < 1 || num > 64 {
-		return errors.New("bit count must be between 1 and 64")
+		return w.writeError("write", uint64(num), ErrInvalidWidth)
 	}
 	if w.offset > 7 {
-		return errors.New("invalid offset")
+		return w.writeError("write", uint64(num), ErrInvalidOffset)
 	}
 	if w.Len() == 0 {
 		w.grow(1)
 	}
 	var (
-		mask    = uint64(1<<num) - 1
-		written = uint8(0)
+		last = w.Len() - 1
+		free = 8 - w.offset
+		word [8]byte
 	)
-	value = value & mask
-	for written < num {
-		var (
-			pending   = num - written
-			remaining = 8 - w.offset
-			length    = min(pending, remaining)
-			shift     = num - written - length
-			mask      = uint8(1<<length) - 1
-			chunk     = (uint8(value>>shift) & mask) << (remaining - length)
-			curr      = w.Buff[w.Len()-1] | chunk
-		)
-		w.Buff[w.Len()-1] = curr
-		w.offset = w.offset + length
-		written = written + length
-		if w.offset == 8 {
-			if err := w.append(); err != nil {
+	value = value & (math.MaxUint64 >> (64 - num))
+	w.record("write", w.bitsWritten, uint64(num), value)
+	w.bitsWritten = w.bitsWritten + uint64(num)
+
+	// Fill the partial byte, finishing early when the value fits inside it
+	if w.order == LSBFirst {
+		w.Buff[last] |= byte(value << w.offset)
+	} else if num < free {
+		w.Buff[last] |= byte(value << (free - num))
+	} else {
+		w.Buff[last] |= byte(value >> (num - free))
+	}
+	if num < free {
+		w.offset = w.offset + num
+		return nil
+	}
+
+	// Store the remaining bits as whole bytes followed by a new partial byte
+	rest := num - free
+	if w.order == LSBFirst {
+		binary.LittleEndian.PutUint64(word[:], value>>free)
+	} else {
+		binary.BigEndian.PutUint64(word[:], value<<(64-rest))
+	}
+	w.Buff = append(w.Buff, word[:rest/8+1]...)
+	w.offset = rest % 8
+	if w.dst != nil && w.Len() > w.capacity {
+		return w.Flush()
+	}
+	return nil
+}
+
+// AlignWrite pads the bit stream up to the next byte boundary.
+func (w *Codec) AlignWrite(pad Padding) error {
+	length := (8 - w.offset) % 8
+	switch pad {
+	case PadZeros:
+		if length > 0 {
+			return w.Write(length, 0)
+		}
+	case PadOnes:
+		if length > 0 {
+			return w.Write(length, math.MaxUint64)
+		}
+	case PadStuffing:
+		if length == 0 {
+			length = 8
+		}
+		if err := w.Write(1, 1); err != nil {
+			return err
+		}
+		if length > 1 {
+			return w.Write(length-1, 0)
+		}
+	default:
+		return w.writeError("align", uint64(length), ErrInvalidArgument)
+	}
+	return nil
+}
+
+// WriteBytes writes data to the bit stream, copying it directly when the stream is byte aligned.
+func (w *Codec) WriteBytes(data []byte) error {
+	if w.offset != 0 {
+		for _, b := range data {
+			if err := w.Write(8, uint64(b)); err != nil {
 				return err
 			}
 		}
+		return nil
 	}
-	w.bitsWritten = w.bitsWritten + uint64(num)
+	if w.Len() == 0 {
+		w.grow(1)
+	}
+	for i, b := range data {
+		w.record("write", w.bitsWritten+8*uint64(i), 8, uint64(b))
+	}
+	w.Buff = append(w.Buff[:w.Len()-1], data...)
+	w.grow(1)
+	w.bitsWritten = w.bitsWritten + 8*uint64(len(data))
+	if w.dst != nil && w.Len() > w.capacity {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes all completed bytes to the underlying writer, keeping the partial byte buffered.
+// It is a no-op for in-memory writers, and while a Reservation has not been set.
+func (w *Codec) Flush() error {
+	if w.dst == nil || w.Len() < 2 || w.reserved > 0 {
+		return nil
+	}
+	last := w.Len() - 1
+	if _, err := w.dst.Write(w.Buff[:last]); err != nil {
+		return err
+	}
+	w.Buff[0] = w.Buff[last]
+	w.Buff = w.Buff[:1]
+	return nil
+}
+
+// Close flushes all completed bytes and the final partial byte, zero padded, to the underlying writer.
+// Reservations that have not been set are written as zeros. It is a no-op for in-memory writers.
+func (w *Codec) Close() error {
+	if w.dst == nil {
+		return nil
+	}
+	w.reserved = 0
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if w.offset > 0 {
+		if _, err := w.dst.Write(w.Buff[:1]); err != nil {
+			return err
+		}
+	}
+	w.Buff = w.Buff[:0]
+	w.offset = 0
 	return nil
 }
 
@@ -111,48 +332,168 @@ func (w *Codec) Read(num uint8) (uint64, error) {
 		return 0, nil
 	}
 	if num > 64 {
-		return 0, errors.New("bit count must be between 1 and 64")
-	}
-	if w.offset > 7 {
-		return 0, errors.New("invalid offset")
+		return 0, w.readError("read", uint64(num), ErrInvalidWidth)
 	}
-	if w.Len() == 0 {
-		return 0, errors.New("buffer is empty")
+	if w.src != nil {
+		if err := w.fill(int(num)); err != nil {
+			return 0, err
+		}
 	}
-
 	// Check if enough bits are available
-	if int(w.available()) < int(num) {
-		return 0, errors.New("not enough bits in buffer")
+	if w.available() < int(num) {
+		return 0, w.readError("read", uint64(num), ErrNotEnoughBits)
 	}
 
 	var (
-		result = uint64(0)
-		read   = uint8(0)
+		index  = w.cursor / 8
+		offset = w.cursor % 8
+		data   = w.Buff[index:]
+		word   [9]byte
+		result uint64
 	)
+	if len(data) < len(word) {
+		copy(word[:], data)
+		data = word[:]
+	}
 
-	for read < num {
-		var (
-			pending   = num - read
-			remaining = uint8(8 - w.offset)
-			length    = min(pending, remaining)
-			shift     = uint8(8 - w.offset - length)
-			mask      = uint8((1<<length)-1) << shift
-			chunk     = uint64((w.Buff[0] & mask) >> shift)
-		)
-		result = (result << length) | chunk
-		w.offset = w.offset + length
-		read = read + length
-
-		if w.offset == 8 {
-			if err := w.advance(); err != nil {
-				return 0, err
-			}
+	// Load 64 bits covering the field, topping up from the ninth byte when it straddles
+	if w.order == LSBFirst {
+		result = binary.LittleEndian.Uint64(data) >> offset
+		if offset > 0 {
+			result = result | uint64(data[8])<<(64-offset)
 		}
+		result = result & (math.MaxUint64 >> (64 - num))
+	} else {
+		result = binary.BigEndian.Uint64(data) << offset
+		if offset > 0 {
+			result = result | uint64(data[8])>>(8-offset)
+		}
+		result = result >> (64 - num)
 	}
+	w.record("read", w.Position(), uint64(num), result)
+	w.cursor = w.cursor + uint64(num)
 	w.bitsRead = w.bitsRead + uint64(num)
 	return result, nil
 }
 
+// AlignRead consumes the padding up to the next byte boundary, checking that it matches pad.
+func (w *Codec) AlignRead(pad Padding) error {
+	length := uint8((8 - w.cursor%8) % 8)
+	var expect uint64
+	switch pad {
+	case PadZeros:
+		expect = 0
+	case PadOnes:
+		expect = math.MaxUint64 >> (64 - length)
+	case PadStuffing:
+		if length == 0 {
+			length = 8
+		}
+		expect = 1 << (length - 1)
+		if w.order == LSBFirst {
+			expect = 1
+		}
+	default:
+		return w.readError("align", uint64(length), ErrInvalidArgument)
+	}
+	position := w.Position()
+	value, err := w.Read(length)
+	if err != nil {
+		return err
+	}
+	if value != expect {
+		return &BitError{Op: "align", Position: position, Width: uint64(length), Err: ErrInvalidPadding}
+	}
+	return nil
+}
+
+// ReadBytes reads num bytes from the bit stream, copying them directly when the stream is byte aligned.
+func (w *Codec) ReadBytes(num int) ([]byte, error) {
+	if num < 0 {
+		return nil, w.readError("read bytes", 0, ErrInvalidArgument)
+	}
+	data := make([]byte, num)
+	if w.cursor%8 != 0 {
+		for i := range data {
+			value, err := w.Read(8)
+			if err != nil {
+				return nil, err
+			}
+			data[i] = byte(value)
+		}
+		return data, nil
+	}
+	if w.src != nil {
+		if err := w.fill(8 * num); err != nil {
+			return nil, err
+		}
+	}
+	if w.available() < 8*num {
+		return nil, w.readError("read bytes", 8*uint64(num), ErrNotEnoughBits)
+	}
+	copy(data, w.Buff[w.cursor/8:])
+	for i, b := range data {
+		w.record("read", w.Position()+8*uint64(i), 8, uint64(b))
+	}
+	w.cursor = w.cursor + 8*uint64(num)
+	w.bitsRead = w.bitsRead + 8*uint64(num)
+	return data, nil
+}
+
+// Peek returns the next num bits from the bit stream without consuming them.
+func (w *Codec) Peek(num uint8) (uint64, error) {
+	var (
+		position = w.Position()
+		count    = w.bitsRead
+		trace    = w.trace
+	)
+	w.trace = nil
+	value, err := w.Read(num)
+	w.cursor = position - w.base
+	w.bitsRead = count
+	w.trace = trace
+	return value, err
+}
+
+// Skip consumes the next num bits from the bit stream, counting them as read.
+func (w *Codec) Skip(num uint64) error {
+	position := w.Position()
+	if err := w.Seek(w.Position() + num); err != nil {
+		return err
+	}
+	w.record("skip", position, num, 0)
+	w.bitsRead = w.bitsRead + num
+	return nil
+}
+
+// Seek moves the read position to the given absolute bit position. It does not change NumRead.
+// A streaming reader discards data while seeking forward, so it cannot seek back to bits that
+// have already been dropped from its buffer, and is left at the end of its data on failure.
+func (w *Codec) Seek(position uint64) error {
+	if position < w.base {
+		return w.readError("seek", 0, ErrInvalidPosition)
+	}
+	for w.src != nil && position-w.base > uint64(8*w.Len()) {
+		w.cursor = uint64(8 * w.Len())
+		if err := w.fill(1); err != nil {
+			return err
+		}
+		if w.available() == 0 {
+			break
+		}
+	}
+	if position-w.base > uint64(8*w.Len()) {
+		return w.readError("seek", position-w.Position(), ErrNotEnoughBits)
+	}
+	w.cursor = position - w.base
+	return nil
+}
+
+// Position returns the absolute read position in bits from the start of the stream.
+func (w *Codec) Position() uint64 {
+	return w.base + w.cursor
+}
+
 // NumWritten returns the total number of bits written.
 func (w *Codec) NumWritten() uint64 {
 	return w.bitsWritten
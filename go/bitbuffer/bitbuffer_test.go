@@ -1,21 +1,23 @@
 package bitbuffer
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
-func ReadWrite(bits uint8, value uint64) error {
-	writer := CreateWriter()
+func ReadWrite(bits uint8, value uint64, opts ...Option) error {
+	writer := CreateWriter(opts...)
 	if err := writer.Write(bits, value); err != nil {
 		return fmt.Errorf("write error: %v", err)
 	}
 	if writer.NumWritten() != uint64(bits) {
 		return fmt.Errorf("unexpected bits written: got %d, want %d", writer.NumWritten(), bits)
 	}
-	reader := CreateReader(writer.Buff)
+	reader := CreateReader(writer.Buff, opts...)
 	temp, err := reader.Read(bits)
 	if err != nil {
 		return fmt.Errorf("read error: %v", err)
@@ -41,21 +43,62 @@ func MaxValueForBits(n int) uint64 {
 
 func TestWriteReadBits(t *testing.T) {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	for bits := 1; bits <= 63; bits++ {
-		mval := uint64(MaxValueForBits(bits))
-		rval := uint64(rng.Int63n(int64(MaxValueForBits(bits))))
-		values := []uint64{0, mval, rval}
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		for bits := 1; bits <= 63; bits++ {
+			mval := uint64(MaxValueForBits(bits))
+			rval := uint64(rng.Int63n(int64(MaxValueForBits(bits))))
+			values := []uint64{0, mval, rval}
+			for _, value := range values {
+				if err := ReadWrite(uint8(bits), value, WithBitOrder(order)); err != nil {
+					t.Errorf("order: %d, bits: %d, value: %d, err: %v", order, bits, value, err)
+				}
+			}
+		}
+		values := []uint64{0, MaxValueForBits(64), rng.Uint64()}
 		for _, value := range values {
-			if err := ReadWrite(uint8(bits), value); err != nil {
-				t.Errorf("bits: %d, value: %d, err: %v", bits, value, err)
+			if err := ReadWrite(64, value, WithBitOrder(order)); err != nil {
+				t.Errorf("order: %d, bits: %d, value: %d, err: %v", order, 64, value, err)
 			}
 		}
 	}
-	values := []uint64{0, MaxValueForBits(64), rng.Uint64()}
-	for _, value := range values {
-		if err := ReadWrite(64, value); err != nil {
-			t.Errorf("bits: %d, value: %d, err: %v", 64, value, err)
-		}
+}
+
+func TestBitOrderVectors(t *testing.T) {
+	tests := []struct {
+		name   string
+		order  BitOrder
+		fields []Tuple
+		want   []byte
+	}{
+		{"msb header", MSBFirst, []Tuple{{1, 1}, {2, 1}, {3, 5}}, []byte{0xB4}},
+		{"lsb header", LSBFirst, []Tuple{{1, 1}, {2, 1}, {3, 5}}, []byte{0x2B}},
+		{"msb word", MSBFirst, []Tuple{{16, 0x1234}}, []byte{0x12, 0x34}},
+		{"lsb word", LSBFirst, []Tuple{{16, 0x1234}}, []byte{0x34, 0x12}},
+		{"msb nibbles", MSBFirst, []Tuple{{12, 0xABC}, {4, 0xD}}, []byte{0xAB, 0xCD}},
+		{"lsb nibbles", LSBFirst, []Tuple{{12, 0xABC}, {4, 0xD}}, []byte{0xBC, 0xDA}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := CreateWriter(WithBitOrder(tt.order))
+			for _, field := range tt.fields {
+				if err := writer.Write(field.Bits, field.Value); err != nil {
+					t.Fatalf("failed writing bits: %d, value: %d, err: %v", field.Bits, field.Value, err)
+				}
+			}
+			if got := writer.Buff[:len(tt.want)]; !bytes.Equal(got, tt.want) {
+				t.Errorf("encoded: got % X, want % X", got, tt.want)
+			}
+			reader := CreateReader(tt.want, WithBitOrder(tt.order))
+			for _, field := range tt.fields {
+				temp, err := reader.Read(field.Bits)
+				if err != nil {
+					t.Fatalf("failed reading bits: %d, err: %v", field.Bits, err)
+				}
+				if temp != field.Value {
+					t.Errorf("mismatch: wrote %d, read %d", field.Value, temp)
+				}
+			}
+		})
 	}
 }
 
@@ -111,3 +154,333 @@ func TestMultipleWriteReadBits(t *testing.T) {
 		}
 	}
 }
+
+func TestStreamWriteReadBits(t *testing.T) {
+	var (
+		rng  = rand.New(rand.NewSource(time.Now().UnixNano()))
+		data = make([]*Tuple, 0)
+		sink = new(bytes.Buffer)
+	)
+	memory := CreateWriter()
+	writer := CreateStreamWriter(sink)
+	for range 1000 {
+		index := uint8(rng.Int63n(64) + 1)
+		value := rng.Uint64() & MaxValueForBits(int(index))
+		if err := writer.Write(index, value); err != nil {
+			t.Fatalf("failed writing bits: %d, value: %d, err: %v", index, value, err)
+		}
+		if err := memory.Write(index, value); err != nil {
+			t.Fatalf("failed writing bits: %d, value: %d, err: %v", index, value, err)
+		}
+		data = append(data, MakeTuple(index, value))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed closing writer: %v", err)
+	}
+	if writer.NumWritten() != memory.NumWritten() {
+		t.Errorf("unexpected bits written: got %d, want %d", writer.NumWritten(), memory.NumWritten())
+	}
+	size := (memory.NumWritten() + 7) / 8
+	if !bytes.Equal(sink.Bytes(), memory.Buff[:size]) {
+		t.Fatalf("stream output differs from in-memory output")
+	}
+	reader := CreateStreamReader(iotest.OneByteReader(bytes.NewReader(sink.Bytes())))
+	for _, item := range data {
+		temp, err := reader.Read(item.Bits)
+		if err != nil {
+			t.Fatalf("failed reading bits: %d, err: %v", item.Bits, err)
+		}
+		if temp != item.Value {
+			t.Errorf("mismatch: wrote %d, read %d", item.Value, temp)
+		}
+	}
+	if reader.NumRead() != writer.NumWritten() {
+		t.Errorf("unexpected bits read: got %d, want %d", reader.NumRead(), writer.NumWritten())
+	}
+	if _, err := reader.Read(8); err == nil {
+		t.Errorf("expected error reading past end of stream")
+	}
+}
+
+func TestPeekSkipSeek(t *testing.T) {
+	data := []byte{0xA5, 0x3C, 0xFF, 0x00, 0x81}
+	readers := map[string]*Codec{
+		"memory": CreateReader(data),
+		"stream": CreateStreamReader(iotest.OneByteReader(bytes.NewReader(data))),
+	}
+	for name, reader := range readers {
+		t.Run(name, func(t *testing.T) {
+			if value, err := reader.Peek(4); err != nil || value != 0xA {
+				t.Errorf("peek: got %X, err: %v, want A", value, err)
+			}
+			if reader.Position() != 0 || reader.NumRead() != 0 {
+				t.Errorf("peek consumed bits: position %d, read %d", reader.Position(), reader.NumRead())
+			}
+			if err := reader.Skip(4); err != nil {
+				t.Fatalf("skip error: %v", err)
+			}
+			if value, err := reader.Read(8); err != nil || value != 0x53 {
+				t.Errorf("read: got %X, err: %v, want 53", value, err)
+			}
+			if reader.Position() != 12 || reader.NumRead() != 12 {
+				t.Errorf("unexpected position %d, read %d, want 12", reader.Position(), reader.NumRead())
+			}
+			if err := reader.Seek(32); err != nil {
+				t.Fatalf("seek error: %v", err)
+			}
+			if value, err := reader.Read(8); err != nil || value != 0x81 {
+				t.Errorf("read: got %X, err: %v, want 81", value, err)
+			}
+			if _, err := reader.Peek(1); err == nil {
+				t.Errorf("expected error peeking past end")
+			}
+			if err := reader.Skip(1); err == nil {
+				t.Errorf("expected error skipping past end")
+			}
+			if err := reader.Seek(41); err == nil {
+				t.Errorf("expected error seeking past end")
+			}
+			if err := reader.Seek(16); err != nil {
+				t.Fatalf("seek error: %v", err)
+			}
+			if value, err := reader.Read(16); err != nil || value != 0xFF00 {
+				t.Errorf("read: got %X, err: %v, want FF00", value, err)
+			}
+		})
+	}
+}
+
+func TestStreamSeekDiscarded(t *testing.T) {
+	data := make([]byte, 4*InitialBufferSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	reader := CreateStreamReader(bytes.NewReader(data))
+	if err := reader.Seek(uint64(8 * (len(data) - 1))); err != nil {
+		t.Fatalf("seek error: %v", err)
+	}
+	if value, err := reader.Read(8); err != nil || value != uint64(data[len(data)-1]) {
+		t.Errorf("read: got %X, err: %v, want %X", value, err, data[len(data)-1])
+	}
+	if err := reader.Seek(0); err == nil {
+		t.Errorf("expected error seeking to discarded data")
+	}
+}
+
+func TestAlignPadding(t *testing.T) {
+	tests := []struct {
+		name  string
+		order BitOrder
+		lead  Tuple
+		pad   Padding
+		want  []byte
+	}{
+		{"msb zeros", MSBFirst, Tuple{3, 7}, PadZeros, []byte{0xE0}},
+		{"msb ones", MSBFirst, Tuple{3, 0}, PadOnes, []byte{0x1F}},
+		{"msb stuffing", MSBFirst, Tuple{3, 7}, PadStuffing, []byte{0xF0}},
+		{"msb stuffing aligned", MSBFirst, Tuple{8, 0xAA}, PadStuffing, []byte{0xAA, 0x80}},
+		{"msb zeros aligned", MSBFirst, Tuple{8, 0xAA}, PadZeros, []byte{0xAA}},
+		{"lsb ones", LSBFirst, Tuple{3, 0}, PadOnes, []byte{0xF8}},
+		{"lsb stuffing", LSBFirst, Tuple{3, 7}, PadStuffing, []byte{0x0F}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := CreateWriter(WithBitOrder(tt.order))
+			if err := writer.Write(tt.lead.Bits, tt.lead.Value); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			if err := writer.AlignWrite(tt.pad); err != nil {
+				t.Fatalf("align error: %v", err)
+			}
+			if writer.NumWritten() != uint64(8*len(tt.want)) {
+				t.Errorf("unexpected bits written: got %d, want %d", writer.NumWritten(), 8*len(tt.want))
+			}
+			if got := writer.Buff[:len(tt.want)]; !bytes.Equal(got, tt.want) {
+				t.Errorf("encoded: got % X, want % X", got, tt.want)
+			}
+			reader := CreateReader(tt.want, WithBitOrder(tt.order))
+			if _, err := reader.Read(tt.lead.Bits); err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			if err := reader.AlignRead(tt.pad); err != nil {
+				t.Fatalf("align error: %v", err)
+			}
+			if reader.NumRead() != writer.NumWritten() {
+				t.Errorf("unexpected bits read: got %d, want %d", reader.NumRead(), writer.NumWritten())
+			}
+		})
+	}
+	reader := CreateReader([]byte{0xE1})
+	if _, err := reader.Read(3); err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if err := reader.AlignRead(PadZeros); err == nil {
+		t.Errorf("expected error for invalid padding")
+	}
+}
+
+func TestWriteReadBytes(t *testing.T) {
+	payload := []byte("bit-level payload")
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		for _, lead := range []uint8{0, 3, 8} {
+			writer := CreateWriter(WithBitOrder(order))
+			if lead > 0 {
+				if err := writer.Write(lead, 5); err != nil {
+					t.Fatalf("write error: %v", err)
+				}
+			}
+			if err := writer.WriteBytes(payload); err != nil {
+				t.Fatalf("write bytes error: %v", err)
+			}
+			if err := writer.Write(5, 17); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			if want := uint64(lead) + 8*uint64(len(payload)) + 5; writer.NumWritten() != want {
+				t.Errorf("unexpected bits written: got %d, want %d", writer.NumWritten(), want)
+			}
+			reader := CreateReader(writer.Buff, WithBitOrder(order))
+			if value, err := reader.Read(lead); err != nil || (lead > 0 && value != 5) {
+				t.Errorf("order: %d, lead: %d, read: got %d, err: %v", order, lead, value, err)
+			}
+			data, err := reader.ReadBytes(len(payload))
+			if err != nil {
+				t.Fatalf("read bytes error: %v", err)
+			}
+			if !bytes.Equal(data, payload) {
+				t.Errorf("order: %d, lead: %d, mismatch: wrote %q, read %q", order, lead, payload, data)
+			}
+			if value, err := reader.Read(5); err != nil || value != 17 {
+				t.Errorf("order: %d, lead: %d, read: got %d, err: %v", order, lead, value, err)
+			}
+			if _, err := reader.ReadBytes(2); err == nil {
+				t.Errorf("expected error reading past end")
+			}
+		}
+	}
+}
+
+// WriteBytewise is the previous byte-at-a-time MSB-first write loop, kept as a benchmark baseline.
+func WriteBytewise(w *Codec, num uint8, value uint64) {
+	if w.Len() == 0 {
+		w.grow(1)
+	}
+	value = value & (uint64(1<<num) - 1)
+	for written := uint8(0); written < num; {
+		var (
+			remaining = 8 - w.offset
+			length    = min(num-written, remaining)
+			mask      = uint8(1<<length) - 1
+		)
+		w.Buff[w.Len()-1] |= (uint8(value>>(num-written-length)) & mask) << (remaining - length)
+		w.offset = w.offset + length
+		written = written + length
+		if w.offset == 8 {
+			w.grow(1)
+			w.offset = 0
+		}
+	}
+	w.bitsWritten = w.bitsWritten + uint64(num)
+}
+
+// ReadBytewise is the previous byte-at-a-time MSB-first read loop, kept as a benchmark baseline.
+func ReadBytewise(w *Codec, num uint8) uint64 {
+	var result uint64
+	for read := uint8(0); read < num; {
+		var (
+			offset    = uint8(w.cursor % 8)
+			remaining = 8 - offset
+			length    = min(num-read, remaining)
+			mask      = uint8(1<<length) - 1
+		)
+		result = (result << length) | uint64((w.Buff[w.cursor/8]>>(remaining-length))&mask)
+		w.cursor = w.cursor + uint64(length)
+		read = read + length
+	}
+	w.bitsRead = w.bitsRead + uint64(num)
+	return result
+}
+
+// FieldWidths is a mix of field widths typical of packed headers.
+var FieldWidths = []uint8{1, 3, 4, 7, 8, 12, 16, 24, 32, 64}
+
+const NumFields = 4096
+
+func FieldBits() uint64 {
+	var total uint64
+	for i := range NumFields {
+		total = total + uint64(FieldWidths[i%len(FieldWidths)])
+	}
+	return total
+}
+
+func BenchmarkWrite(b *testing.B) {
+	b.SetBytes(int64(FieldBits() / 8))
+	for b.Loop() {
+		writer := CreateWriter()
+		for i := range NumFields {
+			writer.Write(FieldWidths[i%len(FieldWidths)], uint64(i)*0x9E3779B97F4A7C15)
+		}
+	}
+}
+
+func BenchmarkWriteBytewise(b *testing.B) {
+	b.SetBytes(int64(FieldBits() / 8))
+	for b.Loop() {
+		writer := CreateWriter()
+		for i := range NumFields {
+			WriteBytewise(writer, FieldWidths[i%len(FieldWidths)], uint64(i)*0x9E3779B97F4A7C15)
+		}
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	writer := CreateWriter()
+	for i := range NumFields {
+		writer.Write(FieldWidths[i%len(FieldWidths)], uint64(i)*0x9E3779B97F4A7C15)
+	}
+	b.SetBytes(int64(FieldBits() / 8))
+	for b.Loop() {
+		reader := CreateReader(writer.Buff)
+		for i := range NumFields {
+			reader.Read(FieldWidths[i%len(FieldWidths)])
+		}
+	}
+}
+
+func BenchmarkReadBytewise(b *testing.B) {
+	writer := CreateWriter()
+	for i := range NumFields {
+		writer.Write(FieldWidths[i%len(FieldWidths)], uint64(i)*0x9E3779B97F4A7C15)
+	}
+	b.SetBytes(int64(FieldBits() / 8))
+	for b.Loop() {
+		reader := CreateReader(writer.Buff)
+		for i := range NumFields {
+			ReadBytewise(reader, FieldWidths[i%len(FieldWidths)])
+		}
+	}
+}
+
+func TestBytewiseBaseline(t *testing.T) {
+	var (
+		fast = CreateWriter()
+		slow = CreateWriter()
+	)
+	for i := range NumFields {
+		fast.Write(FieldWidths[i%len(FieldWidths)], uint64(i)*0x9E3779B97F4A7C15)
+		WriteBytewise(slow, FieldWidths[i%len(FieldWidths)], uint64(i)*0x9E3779B97F4A7C15)
+	}
+	if !bytes.Equal(fast.Buff, slow.Buff) {
+		t.Fatalf("word-at-a-time write differs from bytewise baseline")
+	}
+	reader := CreateReader(fast.Buff)
+	for i := range NumFields {
+		value, err := reader.Read(FieldWidths[i%len(FieldWidths)])
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		if want := ReadBytewise(slow, FieldWidths[i%len(FieldWidths)]); value != want {
+			t.Fatalf("field %d: read %d, bytewise baseline %d", i, value, want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package bitbuffer
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// BitVector is a growable set of bits stored most significant bit first in bytes, the layout of an
+// MSB-first Codec, so that it can be copied to and from a bit stream without per-bit calls. Bits past
+// the length are always zero.
+type BitVector struct {
+	data   []byte
+	length int
+}
+
+// CreateBitVector creates a new BitVector of length bits, all clear.
+func CreateBitVector(length int) *BitVector {
+	length = max(length, 0)
+	return &BitVector{data: make([]byte, (length+7)/8), length: length}
+}
+
+// BitVectorFromBytes creates a BitVector of length bits from data in MSB-first layout. Bits of data
+// past the length are ignored.
+func BitVectorFromBytes(data []byte, length int) *BitVector {
+	v := CreateBitVector(min(length, 8*len(data)))
+	copy(v.data, data)
+	v.trim()
+	return v
+}
+
+// Len returns the number of bits in the vector.
+func (v *BitVector) Len() int {
+	return v.length
+}
+
+// Bytes returns the bits in MSB-first layout, the last byte zero padded. The slice shares the
+// storage of the vector.
+func (v *BitVector) Bytes() []byte {
+	return v.data
+}
+
+// Clone returns a copy of the vector.
+func (v *BitVector) Clone() *BitVector {
+	return &BitVector{data: slices.Clone(v.data), length: v.length}
+}
+
+// trim clears the padding bits of the last byte.
+func (v *BitVector) trim() {
+	if v.length%8 != 0 {
+		v.data[len(v.data)-1] &= 0xFF << (8 - v.length%8)
+	}
+}
+
+// resize grows or shrinks the vector to length bits, new bits being clear.
+func (v *BitVector) resize(length int) {
+	size := (length + 7) / 8
+	if size > len(v.data) {
+		v.data = append(v.data, make([]byte, size-len(v.data))...)
+	}
+	v.data = v.data[:size]
+	v.length = length
+	v.trim()
+}
+
+// Set sets bit i, growing the vector when i is past its end. Negative indices are ignored.
+func (v *BitVector) Set(i int) *BitVector {
+	if i < 0 {
+		return v
+	}
+	if i >= v.length {
+		v.resize(i + 1)
+	}
+	v.data[i/8] |= 0x80 >> (i % 8)
+	return v
+}
+
+// Clear clears bit i.
+func (v *BitVector) Clear(i int) *BitVector {
+	if i >= 0 && i < v.length {
+		v.data[i/8] &^= 0x80 >> (i % 8)
+	}
+	return v
+}
+
+// Test reports whether bit i is set, false for indices outside the vector.
+func (v *BitVector) Test(i int) bool {
+	return i >= 0 && i < v.length && v.data[i/8]&(0x80>>(i%8)) != 0
+}
+
+// Count returns the number of set bits.
+func (v *BitVector) Count() int {
+	count := 0
+	for _, b := range v.data {
+		count = count + bits.OnesCount8(b)
+	}
+	return count
+}
+
+// NextSet returns the index of the first set bit at or after i, reporting whether there is one.
+func (v *BitVector) NextSet(i int) (int, bool) {
+	i = max(i, 0)
+	if i >= v.length {
+		return 0, false
+	}
+	// Mask off the bits before i in its byte, then scan whole bytes
+	index := i / 8
+	b := v.data[index] & (0xFF >> (i % 8))
+	for b == 0 {
+		index++
+		if index == len(v.data) {
+			return 0, false
+		}
+		b = v.data[index]
+	}
+	return 8*index + bits.LeadingZeros8(b), true
+}
+
+// combine applies op byte by byte over the common length, treating missing bits of o as zero.
+func (v *BitVector) combine(o *BitVector, op func(a, b byte) byte) *BitVector {
+	for i := range v.data {
+		var b byte
+		if i < len(o.data) {
+			b = o.data[i]
+		}
+		v.data[i] = op(v.data[i], b)
+	}
+	v.trim()
+	return v
+}
+
+// And clears every bit of v that is not set in o, returning v.
+func (v *BitVector) And(o *BitVector) *BitVector {
+	return v.combine(o, func(a, b byte) byte { return a & b })
+}
+
+// Or sets every bit of v that is set in o, ignoring bits of o past the end of v, and returns v.
+func (v *BitVector) Or(o *BitVector) *BitVector {
+	return v.combine(o, func(a, b byte) byte { return a | b })
+}
+
+// Xor flips every bit of v that is set in o, ignoring bits of o past the end of v, and returns v.
+func (v *BitVector) Xor(o *BitVector) *BitVector {
+	return v.combine(o, func(a, b byte) byte { return a ^ b })
+}
+
+// Not flips every bit of v, returning v.
+func (v *BitVector) Not() *BitVector {
+	for i := range v.data {
+		v.data[i] = ^v.data[i]
+	}
+	v.trim()
+	return v
+}
+
+// Rank returns the number of set bits before bit i.
+func (v *BitVector) Rank(i int) int {
+	i = min(max(i, 0), v.length)
+	count := 0
+	for _, b := range v.data[:i/8] {
+		count = count + bits.OnesCount8(b)
+	}
+	if i%8 != 0 {
+		count = count + bits.OnesCount8(v.data[i/8]&^(0xFF>>(i%8)))
+	}
+	return count
+}
+
+// Select returns the index of the set bit of rank k, counting from zero, reporting whether the
+// vector has more than k set bits.
+func (v *BitVector) Select(k int) (int, bool) {
+	if k < 0 {
+		return 0, false
+	}
+	for index, b := range v.data {
+		count := bits.OnesCount8(b)
+		if k >= count {
+			k = k - count
+			continue
+		}
+		// Drop the k leading set bits, the first bit in the byte being the most significant
+		for ; k > 0; k-- {
+			b = b &^ (0x80 >> bits.LeadingZeros8(b))
+		}
+		return 8*index + bits.LeadingZeros8(b), true
+	}
+	return 0, false
+}
+
+// WriteBitVector writes the bits of v in order, copying whole bytes when the stream is byte aligned
+// and most significant bit first, and 64 bits at a time otherwise.
+func (w *Codec) WriteBitVector(v *BitVector) error {
+	full := v.length / 8
+	if w.offset == 0 && w.order == MSBFirst {
+		if err := w.WriteBytes(v.data[:full]); err != nil {
+			return err
+		}
+	} else {
+		for i := 0; i < full; i = i + 8 {
+			var (
+				n     = min(8, full-i)
+				value uint64
+			)
+			for _, b := range v.data[i : i+n] {
+				value = value<<8 | uint64(b)
+			}
+			if w.order == LSBFirst {
+				value = bits.Reverse64(value) >> (64 - 8*n)
+			}
+			if err := w.Write(uint8(8*n), value); err != nil {
+				return err
+			}
+		}
+	}
+	if rest := uint8(v.length % 8); rest > 0 {
+		value := uint64(v.data[full] >> (8 - rest))
+		if w.order == LSBFirst {
+			value = bits.Reverse64(value) >> (64 - rest)
+		}
+		return w.Write(rest, value)
+	}
+	return nil
+}
+
+// ReadBitVector reads the next length bits into a new BitVector.
+func (w *Codec) ReadBitVector(length int) (*BitVector, error) {
+	if length < 0 {
+		return nil, w.readError("read bits", 0, ErrInvalidArgument)
+	}
+	if w.src == nil && length > w.available() {
+		return nil, w.readError("read bits", uint64(length), ErrNotEnoughBits)
+	}
+	v := CreateBitVector(length)
+	if w.cursor%8 == 0 && w.order == MSBFirst {
+		data, err := w.ReadBytes(length / 8)
+		if err != nil {
+			return nil, err
+		}
+		copy(v.data, data)
+	} else {
+		for i := 0; i < length/8; i = i + 8 {
+			n := min(8, length/8-i)
+			value, err := w.Read(uint8(8 * n))
+			if err != nil {
+				return nil, err
+			}
+			if w.order == LSBFirst {
+				value = bits.Reverse64(value) >> (64 - 8*n)
+			}
+			for j := n - 1; j >= 0; j-- {
+				v.data[i+j] = byte(value)
+				value = value >> 8
+			}
+		}
+	}
+	if rest := uint8(length % 8); rest > 0 {
+		value, err := w.Read(rest)
+		if err != nil {
+			return nil, err
+		}
+		if w.order == LSBFirst {
+			value = bits.Reverse64(value) >> (64 - rest)
+		}
+		v.data[length/8] = byte(value << (8 - rest))
+	}
+	return v, nil
+}
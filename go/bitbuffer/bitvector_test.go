@@ -0,0 +1,169 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// RandomBits returns a random vector of length bits alongside the same bits as a slice.
+func RandomBits(rng *rand.Rand, length int) (*BitVector, []bool) {
+	var (
+		v     = CreateBitVector(length)
+		model = make([]bool, length)
+	)
+	for i := range model {
+		if rng.Intn(3) == 0 {
+			v.Set(i)
+			model[i] = true
+		}
+	}
+	return v, model
+}
+
+func TestBitVector(t *testing.T) {
+	v := CreateBitVector(12)
+	v.Set(0).Set(3).Set(11).Set(4).Clear(4)
+	if !bytes.Equal(v.Bytes(), []byte{0x90, 0x10}) || v.Count() != 3 || v.Len() != 12 {
+		t.Errorf("unexpected vector: % X, count %d, len %d", v.Bytes(), v.Count(), v.Len())
+	}
+	if v.Test(4) || !v.Test(11) || v.Test(12) || v.Test(-1) {
+		t.Errorf("unexpected test results")
+	}
+	v.Set(20)
+	if v.Len() != 21 || !v.Test(20) {
+		t.Errorf("set past the end: len %d", v.Len())
+	}
+	v.Not()
+	if v.Count() != 21-4 || !bytes.Equal(v.Bytes(), []byte{0x6F, 0xEF, 0xF0}) {
+		t.Errorf("not: % X, count %d", v.Bytes(), v.Count())
+	}
+	if got := BitVectorFromBytes([]byte{0xFF, 0xFF}, 10); !bytes.Equal(got.Bytes(), []byte{0xFF, 0xC0}) || got.Count() != 10 {
+		t.Errorf("from bytes: % X", got.Bytes())
+	}
+}
+
+func TestBitVectorQueries(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for range 20 {
+		length := rng.Intn(300)
+		v, model := RandomBits(rng, length)
+		var set []int
+		for i, bit := range model {
+			if bit {
+				set = append(set, i)
+			}
+		}
+		if v.Count() != len(set) {
+			t.Errorf("count: got %d, want %d", v.Count(), len(set))
+		}
+		for i := -1; i <= length+1; i++ {
+			rank := 0
+			for _, index := range set {
+				if index < i {
+					rank++
+				}
+			}
+			if got := v.Rank(i); got != rank {
+				t.Errorf("rank %d: got %d, want %d", i, got, rank)
+			}
+			next, found := v.NextSet(i)
+			want, ok := 0, false
+			for _, index := range set {
+				if index >= i {
+					want, ok = index, true
+					break
+				}
+			}
+			if next != want || found != ok {
+				t.Errorf("next set %d: got %d %t, want %d %t", i, next, found, want, ok)
+			}
+		}
+		for k, index := range set {
+			if got, ok := v.Select(k); !ok || got != index || v.Rank(got) != k {
+				t.Errorf("select %d: got %d %t, want %d", k, got, ok, index)
+			}
+		}
+		if _, ok := v.Select(len(set)); ok {
+			t.Errorf("select %d past the last set bit succeeded", len(set))
+		}
+	}
+}
+
+func TestBitVectorLogic(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for range 20 {
+		length := rng.Intn(200)
+		a, x := RandomBits(rng, length)
+		b, y := RandomBits(rng, length)
+		and, or, xor := a.Clone().And(b), a.Clone().Or(b), a.Clone().Xor(b)
+		for i := range length {
+			if and.Test(i) != (x[i] && y[i]) || or.Test(i) != (x[i] || y[i]) || xor.Test(i) != (x[i] != y[i]) {
+				t.Fatalf("bit %d: and %t, or %t, xor %t for %t, %t", i, and.Test(i), or.Test(i), xor.Test(i), x[i], y[i])
+			}
+		}
+		if a.Clone().Not().Count() != length-a.Count() {
+			t.Errorf("not: count mismatch")
+		}
+	}
+
+	// Shorter operands count as zero padded, longer ones are cut to the receiver
+	short := CreateBitVector(4).Set(1)
+	long := CreateBitVector(16).Set(1).Set(12)
+	if got := long.Clone().And(short); got.Count() != 1 || got.Len() != 16 {
+		t.Errorf("and shorter: count %d, len %d", got.Count(), got.Len())
+	}
+	if got := short.Clone().Or(long); got.Count() != 1 || got.Len() != 4 {
+		t.Errorf("or longer: count %d, len %d", got.Count(), got.Len())
+	}
+}
+
+func TestBitVectorCodec(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		for range 50 {
+			var (
+				lead      = uint8(rng.Intn(8))
+				v, model  = RandomBits(rng, rng.Intn(200))
+				w         = CreateWriter(WithBitOrder(order))
+				reference = CreateWriter(WithBitOrder(order))
+			)
+			if lead > 0 {
+				w.Write(lead, 0x55)
+				reference.Write(lead, 0x55)
+			}
+			if err := w.WriteBitVector(v); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			for _, bit := range model {
+				if bit {
+					reference.Write(1, 1)
+				} else {
+					reference.Write(1, 0)
+				}
+			}
+			if !bytes.Equal(w.Buff, reference.Buff) || w.NumWritten() != reference.NumWritten() {
+				t.Fatalf("order %d, lead %d, %d bits: got % X, want % X", order, lead, len(model), w.Buff, reference.Buff)
+			}
+
+			r := CreateReader(w.Buff, WithBitOrder(order))
+			if lead > 0 {
+				r.Read(lead)
+			}
+			got, err := r.ReadBitVector(v.Len())
+			if err != nil || !bytes.Equal(got.Bytes(), v.Bytes()) || got.Len() != v.Len() {
+				t.Fatalf("order %d, lead %d: read % X, err: %v, want % X", order, lead, got.Bytes(), err, v.Bytes())
+			}
+		}
+	}
+	if _, err := CreateReader([]byte{0xFF}).ReadBitVector(9); err == nil {
+		t.Errorf("expected error reading past the end")
+	}
+	// A huge length fails before the vector is allocated
+	if _, err := CreateReader([]byte{0xFF}).ReadBitVector(math.MaxInt); !errors.Is(err, ErrNotEnoughBits) {
+		t.Errorf("expected ErrNotEnoughBits, got %v", err)
+	}
+}
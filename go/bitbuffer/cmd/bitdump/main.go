@@ -0,0 +1,201 @@
+// Command bitdump decodes a hex, binary or raw blob against a bit-field layout, or encodes field
+// values back to hex.
+//
+// A layout lists fields as name:width pairs separated by spaces or commas, widths from 1 to 64:
+//
+//	bitdump -spec "version:4 ihl:4 tos:8 len:16" packet.hex
+//	echo 45 00 00 14 | bitdump -spec "version:4 ihl:4 tos:8 len:16"
+//	bitdump -spec "version:4 ihl:4 tos:8 len:16" -encode version=4 ihl=5 tos=0 len=20
+//
+// Decoding prints each field with its bit offset, width, bits and decimal value, followed by the
+// number of bits left over. Encoding accepts decimal, 0x, 0o and 0b values, negative values being
+// written in two's complement.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"playground/go/bitbuffer"
+)
+
+// Field is one entry of a layout.
+type Field struct {
+	Name  string
+	Width uint8
+}
+
+// ParseSpec parses a layout of name:width fields.
+func ParseSpec(spec string) ([]Field, error) {
+	var fields []Field
+	for _, item := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+		name, width, ok := strings.Cut(item, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("field %q: expected name:width", item)
+		}
+		num, err := strconv.ParseUint(width, 10, 8)
+		if err != nil || num < 1 || num > 64 {
+			return nil, fmt.Errorf("field %q: width must be between 1 and 64", item)
+		}
+		fields = append(fields, Field{Name: name, Width: uint8(num)})
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("empty field spec")
+	}
+	return fields, nil
+}
+
+// ParseInput converts the input to bytes according to format: "hex" ignores whitespace, colons
+// and 0x prefixes, "bin" ignores everything but 0 and 1 and zero pads the last byte, "raw" takes
+// the bytes as they are.
+func ParseInput(data []byte, format string) ([]byte, int, error) {
+	switch format {
+	case "hex":
+		text := strings.ReplaceAll(strings.ToLower(string(data)), "0x", "")
+		text = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(" \t\r\n:", r) {
+				return -1
+			}
+			return r
+		}, text)
+		decoded, err := hex.DecodeString(text)
+		return decoded, 8 * len(decoded), err
+	case "bin":
+		w := bitbuffer.CreateWriter()
+		for _, c := range data {
+			if c == '0' || c == '1' {
+				w.Write(1, uint64(c-'0'))
+			}
+		}
+		return w.Buff[:(w.NumWritten()+7)/8], int(w.NumWritten()), nil
+	case "raw":
+		return data, 8 * len(data), nil
+	}
+	return nil, 0, fmt.Errorf("unknown input format %q", format)
+}
+
+// Decode reads the fields from data, holding size bits, and writes the annotated dump to out.
+func Decode(out io.Writer, data []byte, size int, fields []Field, order bitbuffer.BitOrder) error {
+	r := bitbuffer.CreateReader(data, bitbuffer.WithBitOrder(order), bitbuffer.WithTrace())
+	var err error
+	for _, field := range fields {
+		if int(r.Position())+int(field.Width) > size {
+			err = fmt.Errorf("field %s: needs %d bits, %d left", field.Name, field.Width, size-int(r.Position()))
+			break
+		}
+		if _, err = r.Label(field.Name).Read(field.Width); err != nil {
+			break
+		}
+	}
+	if dumpErr := r.Trace().Dump(out); dumpErr != nil {
+		return dumpErr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%d bits remaining\n", size-int(r.Position()))
+	return err
+}
+
+// Encode writes the named values in layout order and returns the result as hex. Every field must
+// be given exactly once.
+func Encode(fields []Field, assignments []string, order bitbuffer.BitOrder) (string, error) {
+	values := make(map[string]string)
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return "", fmt.Errorf("value %q: expected name=value", assignment)
+		}
+		if _, ok := values[name]; ok {
+			return "", fmt.Errorf("field %s: given twice", name)
+		}
+		values[name] = value
+	}
+	w := bitbuffer.CreateWriter(bitbuffer.WithBitOrder(order))
+	for _, field := range fields {
+		text, ok := values[field.Name]
+		if !ok {
+			return "", fmt.Errorf("field %s: no value", field.Name)
+		}
+		delete(values, field.Name)
+		if strings.HasPrefix(text, "-") {
+			value, err := strconv.ParseInt(text, 0, 64)
+			if err != nil {
+				return "", fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			if err := w.WriteSigned(field.Width, value); err != nil {
+				return "", fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			continue
+		}
+		value, err := strconv.ParseUint(text, 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if field.Width < 64 && value>>field.Width != 0 {
+			return "", fmt.Errorf("field %s: %d does not fit in %d bits", field.Name, value, field.Width)
+		}
+		w.Write(field.Width, value)
+	}
+	for name := range values {
+		return "", fmt.Errorf("field %s: not in spec", name)
+	}
+	return hex.EncodeToString(w.Buff[:(w.NumWritten()+7)/8]), nil
+}
+
+func main() {
+	var (
+		spec   = flag.String("spec", "", "field layout, e.g. \"version:4 ihl:4 tos:8 len:16\"")
+		format = flag.String("input", "hex", "input format: hex, bin or raw")
+		lsb    = flag.Bool("lsb", false, "pack bits least significant bit first")
+		encode = flag.Bool("encode", false, "encode name=value arguments to hex instead of decoding")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("bitdump: ")
+
+	fields, err := ParseSpec(*spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	order := bitbuffer.MSBFirst
+	if *lsb {
+		order = bitbuffer.LSBFirst
+	}
+
+	if *encode {
+		encoded, err := Encode(fields, flag.Args(), order)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(encoded)
+		return
+	}
+
+	var input []byte
+	switch flag.NArg() {
+	case 0:
+		input, err = io.ReadAll(os.Stdin)
+	case 1:
+		input, err = os.ReadFile(flag.Arg(0))
+	default:
+		log.Fatal("expected at most one input file")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, size, err := ParseInput(input, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := Decode(os.Stdout, data, size, fields, order); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"playground/go/bitbuffer"
+)
+
+func TestDecode(t *testing.T) {
+	fields, err := ParseSpec("version:4 ihl:4, tos:8 len:16")
+	if err != nil {
+		t.Fatalf("spec error: %v", err)
+	}
+	data, size, err := ParseInput([]byte("0x45 00\n00:14 ff"), "hex")
+	if err != nil || size != 40 {
+		t.Fatalf("input error: %v, size %d", err, size)
+	}
+	var out bytes.Buffer
+	if err := Decode(&out, data, size, fields, bitbuffer.MSBFirst); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	for _, want := range []string{
+		"       4      4 read  .... 0101           = 5                    ihl",
+		"      16     16 read  0000 0000 0001 0100 = 20                   len",
+		"8 bits remaining",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+
+	data, size, _ = ParseInput([]byte("0100 0101 1"), "bin")
+	if err := Decode(&out, data, size, fields, bitbuffer.MSBFirst); err == nil {
+		t.Errorf("expected error decoding %d bits", size)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	fields, _ := ParseSpec("version:4 ihl:4 tos:8 len:16 delta:4")
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"version=4", "ihl=5", "tos=0", "len=20", "delta=0"}, "45000014" + "00"},
+		{[]string{"delta=-1", "len=0x14", "tos=0b11", "ihl=5", "version=4"}, "450300" + "14f0"},
+		{[]string{"version=16", "ihl=5", "tos=0", "len=20", "delta=0"}, ""},
+		{[]string{"version=4", "ihl=5", "tos=0", "len=20"}, ""},
+		{[]string{"version=4", "ihl=5", "tos=0", "len=20", "delta=0", "extra=1"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := Encode(fields, tt.values, bitbuffer.MSBFirst)
+		if got != tt.want || (err == nil) != (tt.want != "") {
+			t.Errorf("%v: got %q, err: %v, want %q", tt.values, got, err, tt.want)
+		}
+	}
+	if _, err := ParseSpec("version:65"); err == nil {
+		t.Errorf("expected error for width 65")
+	}
+}
@@ -0,0 +1,179 @@
+package bitbuffer
+
+import "math"
+
+// CRC describes a cyclic redundancy check in the Rocksoft model used by the CRC RevEng catalogue.
+type CRC struct {
+	Name   string // Catalogue name
+	Width  uint8  // Register width in bits, 1 to 64
+	Poly   uint64 // Generator polynomial without the leading term, not reflected
+	Init   uint64 // Initial register value, not reflected
+	RefIn  bool   // Process each input chunk least significant bit first
+	RefOut bool   // Reflect the register before the final XOR
+	XorOut uint64 // Value XORed into the final register
+	Check  uint64 // CRC of the ASCII string "123456789"
+}
+
+// Common CRC parameterizations, named after their entries in the CRC RevEng catalogue.
+var (
+	CRC5EPC      = CRC{Name: "CRC-5/EPC-C1G2", Width: 5, Poly: 0x09, Init: 0x09, Check: 0x00}
+	CRC5ITU      = CRC{Name: "CRC-5/G-704", Width: 5, Poly: 0x15, RefIn: true, RefOut: true, Check: 0x07}
+	CRC5USB      = CRC{Name: "CRC-5/USB", Width: 5, Poly: 0x05, Init: 0x1F, RefIn: true, RefOut: true, XorOut: 0x1F, Check: 0x19}
+	CRC8         = CRC{Name: "CRC-8/SMBUS", Width: 8, Poly: 0x07, Check: 0xF4}
+	CRC8Maxim    = CRC{Name: "CRC-8/MAXIM-DOW", Width: 8, Poly: 0x31, RefIn: true, RefOut: true, Check: 0xA1}
+	CRC11FlexRay = CRC{Name: "CRC-11/FLEXRAY", Width: 11, Poly: 0x385, Init: 0x01A, Check: 0x5A3}
+	CRC15CAN     = CRC{Name: "CRC-15/CAN", Width: 15, Poly: 0x4599, Check: 0x059E}
+	CRC16CCITT   = CRC{Name: "CRC-16/IBM-3740", Width: 16, Poly: 0x1021, Init: 0xFFFF, Check: 0x29B1}
+	CRC16Kermit  = CRC{Name: "CRC-16/KERMIT", Width: 16, Poly: 0x1021, RefIn: true, RefOut: true, Check: 0x2189}
+	CRC16XModem  = CRC{Name: "CRC-16/XMODEM", Width: 16, Poly: 0x1021, Check: 0x31C3}
+	CRC16X25     = CRC{Name: "CRC-16/IBM-SDLC", Width: 16, Poly: 0x1021, Init: 0xFFFF, RefIn: true, RefOut: true, XorOut: 0xFFFF, Check: 0x906E}
+	CRC16ARC     = CRC{Name: "CRC-16/ARC", Width: 16, Poly: 0x8005, RefIn: true, RefOut: true, Check: 0xBB3D}
+	CRC24A       = CRC{Name: "CRC-24/LTE-A", Width: 24, Poly: 0x864CFB, Check: 0xCDE703}
+	CRC24B       = CRC{Name: "CRC-24/LTE-B", Width: 24, Poly: 0x800063, Check: 0x23EF52}
+	CRC24OpenPGP = CRC{Name: "CRC-24/OPENPGP", Width: 24, Poly: 0x864CFB, Init: 0xB704CE, Check: 0x21CF02}
+	CRC32        = CRC{Name: "CRC-32/ISO-HDLC", Width: 32, Poly: 0x04C11DB7, Init: 0xFFFFFFFF, RefIn: true, RefOut: true, XorOut: 0xFFFFFFFF, Check: 0xCBF43926}
+	CRC32C       = CRC{Name: "CRC-32/ISCSI", Width: 32, Poly: 0x1EDC6F41, Init: 0xFFFFFFFF, RefIn: true, RefOut: true, XorOut: 0xFFFFFFFF, Check: 0xE3069283}
+	CRC32BZIP2   = CRC{Name: "CRC-32/BZIP2", Width: 32, Poly: 0x04C11DB7, Init: 0xFFFFFFFF, XorOut: 0xFFFFFFFF, Check: 0xFC891918}
+	CRC32MPEG2   = CRC{Name: "CRC-32/MPEG-2", Width: 32, Poly: 0x04C11DB7, Init: 0xFFFFFFFF, Check: 0x0376E6E7}
+	CRC64XZ      = CRC{Name: "CRC-64/XZ", Width: 64, Poly: 0x42F0E1EBA9EA3693, Init: math.MaxUint64, RefIn: true, RefOut: true, XorOut: math.MaxUint64, Check: 0x995DC9BBDF1939FA}
+	CRC64ECMA    = CRC{Name: "CRC-64/ECMA-182", Width: 64, Poly: 0x42F0E1EBA9EA3693, Check: 0x6C40DF5F0B497347}
+	CRCCatalog   = []CRC{CRC5EPC, CRC5ITU, CRC5USB, CRC8, CRC8Maxim, CRC11FlexRay, CRC15CAN, CRC16CCITT, CRC16Kermit, CRC16XModem, CRC16X25, CRC16ARC, CRC24A, CRC24B, CRC24OpenPGP, CRC32, CRC32C, CRC32BZIP2, CRC32MPEG2, CRC64XZ, CRC64ECMA}
+)
+
+// LookupCRC returns the catalogued CRC with the given name.
+func LookupCRC(name string) (CRC, bool) {
+	for _, c := range CRCCatalog {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return CRC{}, false
+}
+
+// mask returns the register mask of the CRC.
+func (c CRC) mask() uint64 {
+	return math.MaxUint64 >> (64 - c.Width)
+}
+
+// update feeds num bits of value into the register, most significant first unless RefIn is set.
+func (c CRC) update(register, value uint64, num uint8) uint64 {
+	top := uint64(1) << (c.Width - 1)
+	for i := range num {
+		shift := num - 1 - i
+		if c.RefIn {
+			shift = i
+		}
+		bit := value >> shift & 1
+		if (register&top != 0) != (bit == 1) {
+			register = register<<1 ^ c.Poly
+		} else {
+			register = register << 1
+		}
+		register = register & c.mask()
+	}
+	return register
+}
+
+// finish applies the output reflection and XOR to the register.
+func (c CRC) finish(register uint64) uint64 {
+	if c.RefOut {
+		register = reverse(register, c.Width)
+	}
+	return (register ^ c.XorOut) & c.mask()
+}
+
+// reverse returns the num least significant bits of value in reverse order.
+func reverse(value uint64, num uint8) uint64 {
+	var result uint64
+	for range num {
+		result = result<<1 | value&1
+		value = value >> 1
+	}
+	return result
+}
+
+// Checksum returns the CRC of data.
+func (c CRC) Checksum(data []byte) uint64 {
+	register := c.Init & c.mask()
+	for _, b := range data {
+		register = c.update(register, uint64(b), 8)
+	}
+	return c.finish(register)
+}
+
+// compute returns the CRC of the next num bits of r, taken as bytes as returned by Read(8) and a
+// final shorter chunk.
+func (c CRC) compute(r *Codec, num uint64) (uint64, error) {
+	register := c.Init & c.mask()
+	for num > 0 {
+		chunk := uint8(min(num, 8))
+		value, err := r.Read(chunk)
+		if err != nil {
+			return 0, err
+		}
+		register = c.update(register, value, chunk)
+		num = num - uint64(chunk)
+	}
+	return c.finish(register), nil
+}
+
+// Mark records the read and write positions of the Codec as the start of a checksummed range.
+type Mark struct {
+	read    uint64
+	written uint64
+}
+
+// Mark returns the current read and write positions for a later CRC computation.
+func (w *Codec) Mark() Mark {
+	return Mark{read: w.Position(), written: w.bitsWritten}
+}
+
+// CRCWritten returns the CRC of the bits written since the mark. A streaming writer fails with
+// ErrInvalidPosition once the start of the range has been flushed.
+func (w *Codec) CRCWritten(m Mark, c CRC) (uint64, error) {
+	flushed := w.flushed()
+	if m.written < flushed || m.written > w.bitsWritten {
+		return 0, w.writeError("crc", 0, ErrInvalidPosition)
+	}
+	r := CreateReader(w.Buff, WithBitOrder(w.order))
+	r.cursor = m.written - flushed
+	return c.compute(r, w.bitsWritten-m.written)
+}
+
+// CRCRead returns the CRC of the bits read since the mark. A streaming reader fails with
+// ErrInvalidPosition once the start of the range has been discarded.
+func (w *Codec) CRCRead(m Mark, c CRC) (uint64, error) {
+	if m.read < w.base || m.read > w.Position() {
+		return 0, w.readError("crc", 0, ErrInvalidPosition)
+	}
+	r := CreateReader(w.Buff, WithBitOrder(w.order))
+	r.cursor = m.read - w.base
+	return c.compute(r, w.Position()-m.read)
+}
+
+// WriteCRC appends the CRC of the bits written since the mark.
+func (w *Codec) WriteCRC(m Mark, c CRC) error {
+	crc, err := w.CRCWritten(m, c)
+	if err != nil {
+		return err
+	}
+	return w.Write(c.Width, crc)
+}
+
+// ReadCRC reads a CRC and checks it against the bits read since the mark, failing with
+// ErrChecksum on a mismatch.
+func (w *Codec) ReadCRC(m Mark, c CRC) error {
+	crc, err := w.CRCRead(m, c)
+	if err != nil {
+		return err
+	}
+	position := w.Position()
+	value, err := w.Read(c.Width)
+	if err != nil {
+		return err
+	}
+	if value != crc {
+		return &BitError{Op: "crc", Position: position, Width: uint64(c.Width), Err: ErrChecksum}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package bitbuffer
+
+import (
+	"errors"
+	"hash/crc32"
+	"hash/crc64"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCRCCatalog(t *testing.T) {
+	for _, c := range CRCCatalog {
+		if got := c.Checksum([]byte("123456789")); got != c.Check {
+			t.Errorf("%s: got %#x, want %#x", c.Name, got, c.Check)
+		}
+		if found, ok := LookupCRC(c.Name); !ok || found != c {
+			t.Errorf("%s: lookup failed", c.Name)
+		}
+	}
+}
+
+func TestCRCStandardLibrary(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	data := make([]byte, rng.Intn(256))
+	rng.Read(data)
+	if got, want := CRC32.Checksum(data), uint64(crc32.ChecksumIEEE(data)); got != want {
+		t.Errorf("%s: got %#x, want %#x", CRC32.Name, got, want)
+	}
+	if got, want := CRC32C.Checksum(data), uint64(crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))); got != want {
+		t.Errorf("%s: got %#x, want %#x", CRC32C.Name, got, want)
+	}
+	if got, want := CRC64XZ.Checksum(data), crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)); got != want {
+		t.Errorf("%s: got %#x, want %#x", CRC64XZ.Name, got, want)
+	}
+}
+
+func TestCRCUnaligned(t *testing.T) {
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		for _, c := range CRCCatalog {
+			w := CreateWriter(WithBitOrder(order))
+			w.Write(3, 5)
+			mark := w.Mark()
+			w.WriteBytes([]byte("123456789"))
+			if err := w.WriteCRC(mark, c); err != nil {
+				t.Fatalf("%s: write error: %v", c.Name, err)
+			}
+			w.Write(5, 0)
+
+			r := CreateReader(w.Buff, WithBitOrder(order))
+			r.Read(3)
+			mark = r.Mark()
+			r.ReadBytes(9)
+			if crc, err := r.CRCRead(mark, c); err != nil || crc != c.Check {
+				t.Errorf("%s: got %#x, err: %v, want %#x", c.Name, crc, err, c.Check)
+			}
+			if err := r.ReadCRC(mark, c); err != nil {
+				t.Errorf("%s: check error: %v", c.Name, err)
+			}
+		}
+	}
+}
+
+func TestCRCResidue(t *testing.T) {
+	// Without initial value or final XOR, a message followed by its CRC has a CRC of zero
+	// whatever its length in bits
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	tests := []struct {
+		crc   CRC
+		order BitOrder
+	}{
+		{CRC15CAN, MSBFirst},
+		{CRC24A, MSBFirst},
+		{CRC24B, MSBFirst},
+		{CRC16Kermit, LSBFirst},
+		{CRC5ITU, LSBFirst},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			w := CreateWriter(WithBitOrder(tt.order))
+			mark := w.Mark()
+			for range rng.Intn(20) + 1 {
+				width := uint8(rng.Intn(64) + 1)
+				w.Write(width, rng.Uint64())
+			}
+			if err := w.WriteCRC(mark, tt.crc); err != nil {
+				t.Fatalf("%s: write error: %v", tt.crc.Name, err)
+			}
+			if residue, err := w.CRCWritten(mark, tt.crc); err != nil || residue != 0 {
+				t.Errorf("%s: %d bits, residue %#x, err: %v", tt.crc.Name, w.NumWritten(), residue, err)
+			}
+		}
+	}
+}
+
+func TestCRCErrors(t *testing.T) {
+	w := CreateWriter()
+	mark := w.Mark()
+	w.Write(11, 0x5A5)
+	w.WriteCRC(mark, CRC5USB)
+	w.Buff[0] ^= 0x10
+
+	r := CreateReader(w.Buff)
+	mark = r.Mark()
+	r.Read(11)
+	if err := r.ReadCRC(mark, CRC5USB); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected ErrChecksum, got %v", err)
+	}
+	r.Seek(0)
+	if _, err := r.CRCRead(Mark{read: 8}, CRC5USB); !errors.Is(err, ErrInvalidPosition) {
+		t.Errorf("expected ErrInvalidPosition, got %v", err)
+	}
+}
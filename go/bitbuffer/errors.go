@@ -0,0 +1,78 @@
+package bitbuffer
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidWidth reports a bit count outside 1 to 64, a programming error.
+	ErrInvalidWidth = errors.New("bit count must be between 1 and 64")
+	// ErrInvalidOffset reports a corrupted Codec whose bit offset is outside 0 to 7.
+	ErrInvalidOffset = errors.New("invalid offset")
+	// ErrInvalidArgument reports an argument outside the domain of an operation, a programming error.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrNotEnoughBits reports truncated input: fewer bits remain than an operation requires.
+	ErrNotEnoughBits = errors.New("not enough bits in buffer")
+	// ErrInvalidPosition reports a seek to bits a streaming reader has already discarded.
+	ErrInvalidPosition = errors.New("position before buffered data")
+	// ErrInvalidPadding reports alignment padding that does not match the expected pattern.
+	ErrInvalidPadding = errors.New("invalid padding")
+	// ErrInvalidCode reports a malformed variable length code in the input.
+	ErrInvalidCode = errors.New("invalid variable length code")
+	// ErrOverlong reports a varint that uses more bytes than its value requires.
+	ErrOverlong = errors.New("overlong varint encoding")
+	// ErrChecksum reports a CRC in the input that does not match the data it covers.
+	ErrChecksum = errors.New("checksum mismatch")
+	// ErrRange reports a value that cannot be represented by the requested encoding.
+	ErrRange = errors.New("value out of range")
+)
+
+// BitError describes a failed operation on the bit stream. It wraps one of the sentinel errors,
+// so callers can test it with errors.Is and inspect it with errors.As.
+type BitError struct {
+	Op        string // Operation that failed, such as "read" or "write"
+	Position  uint64 // Bit position of the operation in the stream
+	Width     uint64 // Number of bits requested
+	Available uint64 // Number of bits available to the operation
+	Err       error  // Underlying sentinel error
+}
+
+func (e *BitError) Error() string {
+	if errors.Is(e.Err, ErrNotEnoughBits) {
+		return fmt.Sprintf("%s of %d bits at bit %d: %v (%d available)", e.Op, e.Width, e.Position, e.Err, e.Available)
+	}
+	return fmt.Sprintf("%s of %d bits at bit %d: %v", e.Op, e.Width, e.Position, e.Err)
+}
+
+func (e *BitError) Unwrap() error {
+	return e.Err
+}
+
+// RangeError reports a value that does not fit in the requested number of bits.
+type RangeError struct {
+	Value    int64 // Value being written
+	Bits     uint8 // Requested field width
+	Unsigned bool  // Value holds the bits of an unsigned value
+}
+
+func (e *RangeError) Error() string {
+	if e.Unsigned {
+		return fmt.Sprintf("value %d does not fit in %d bits", uint64(e.Value), e.Bits)
+	}
+	return fmt.Sprintf("value %d does not fit in %d bits", e.Value, e.Bits)
+}
+
+func (e *RangeError) Unwrap() error {
+	return ErrRange
+}
+
+// readError creates a BitError for a read-side operation at the current read position.
+func (w *Codec) readError(op string, width uint64, err error) error {
+	return &BitError{Op: op, Position: w.Position(), Width: width, Available: uint64(max(w.available(), 0)), Err: err}
+}
+
+// writeError creates a BitError for a write-side operation at the current write position.
+func (w *Codec) writeError(op string, width uint64, err error) error {
+	return &BitError{Op: op, Position: w.bitsWritten, Width: width, Err: err}
+}
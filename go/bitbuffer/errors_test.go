@@ -0,0 +1,90 @@
+package bitbuffer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBitErrors(t *testing.T) {
+	reader := CreateReader([]byte{0xF0, 0x0F})
+	if _, err := reader.Read(12); err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	_, err := reader.Read(8)
+	var bitErr *BitError
+	if !errors.As(err, &bitErr) {
+		t.Fatalf("expected BitError, got %v", err)
+	}
+	if !errors.Is(err, ErrNotEnoughBits) {
+		t.Errorf("expected ErrNotEnoughBits, got %v", err)
+	}
+	if bitErr.Op != "read" || bitErr.Position != 12 || bitErr.Width != 8 || bitErr.Available != 4 {
+		t.Errorf("unexpected error fields: %+v", bitErr)
+	}
+	if reader.Position() != 12 || reader.NumRead() != 12 {
+		t.Errorf("failed read moved position: %d, read %d", reader.Position(), reader.NumRead())
+	}
+
+	tests := []struct {
+		name   string
+		call   func() error
+		target error
+	}{
+		{"read width", func() error { _, err := CreateReader([]byte{0}).Read(65); return err }, ErrInvalidWidth},
+		{"write width", func() error { return CreateWriter().Write(0, 1) }, ErrInvalidWidth},
+		{"signed width", func() error { _, err := CreateReader([]byte{0}).ReadSigned(0); return err }, ErrInvalidWidth},
+		{"peek short", func() error { _, err := CreateReader(nil).Peek(1); return err }, ErrNotEnoughBits},
+		{"skip short", func() error { return CreateReader([]byte{0}).Skip(9) }, ErrNotEnoughBits},
+		{"seek short", func() error { return CreateReader([]byte{0}).Seek(9) }, ErrNotEnoughBits},
+		{"read bytes short", func() error { _, err := CreateReader([]byte{0}).ReadBytes(2); return err }, ErrNotEnoughBits},
+		{"read bytes negative", func() error { _, err := CreateReader(nil).ReadBytes(-1); return err }, ErrInvalidArgument},
+		{"padding", func() error {
+			reader := CreateReader([]byte{0x81})
+			reader.Read(1)
+			return reader.AlignRead(PadZeros)
+		}, ErrInvalidPadding},
+		{"code", func() error { _, err := CreateReader(make([]byte, 16)).ReadExpGolomb(); return err }, ErrInvalidCode},
+		{"signed range", func() error { return CreateWriter().WriteSigned(4, 8) }, ErrRange},
+		{"gamma range", func() error { return CreateWriter().WriteEliasGamma(0) }, ErrRange},
+		{"golomb divisor", func() error { return CreateWriter().WriteGolomb(1, 0) }, ErrInvalidArgument},
+		{"marshal range", func() error {
+			_, err := Marshal(struct {
+				A uint8 `bits:"2"`
+			}{4})
+			return err
+		}, ErrRange},
+		{"unmarshal short", func() error {
+			var header IPv4Header
+			return Unmarshal([]byte{0x45}, &header)
+		}, ErrNotEnoughBits},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.target) {
+				t.Errorf("expected %v, got %v", tt.target, err)
+			}
+		})
+	}
+}
+
+func TestBitErrorPosition(t *testing.T) {
+	writer := CreateWriter()
+	if err := writer.Write(5, 1); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	err := writer.Write(70, 1)
+	var bitErr *BitError
+	if !errors.As(err, &bitErr) {
+		t.Fatalf("expected BitError, got %v", err)
+	}
+	if bitErr.Op != "write" || bitErr.Position != 5 || bitErr.Width != 70 {
+		t.Errorf("unexpected error fields: %+v", bitErr)
+	}
+	reader := CreateReader([]byte{0x81})
+	reader.Read(1)
+	err = reader.AlignRead(PadZeros)
+	if !errors.As(err, &bitErr) || bitErr.Position != 1 || bitErr.Width != 7 {
+		t.Errorf("unexpected padding error: %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+// Reference is the model for the Codec: a plain slice of bits in stream order.
+type Reference []bool
+
+// Append adds the num least significant bits of value in stream order.
+func (v Reference) Append(num uint8, value uint64, order BitOrder) Reference {
+	for i := range num {
+		shift := num - 1 - i
+		if order == LSBFirst {
+			shift = i
+		}
+		v = append(v, value>>shift&1 == 1)
+	}
+	return v
+}
+
+// Value returns the num bits starting at position as a Codec would read them.
+func (v Reference) Value(position int, num uint8, order BitOrder) uint64 {
+	var value uint64
+	for i := range int(num) {
+		if !v[position+i] {
+			continue
+		}
+		if order == LSBFirst {
+			value = value | 1<<i
+		} else {
+			value = value | 1<<(int(num)-1-i)
+		}
+	}
+	return value
+}
+
+// Bytes packs the bits into bytes, zero padding the last one.
+func (v Reference) Bytes(order BitOrder) []byte {
+	data := make([]byte, (len(v)+7)/8)
+	for i, bit := range v {
+		if !bit {
+			continue
+		}
+		if order == LSBFirst {
+			data[i/8] |= 1 << (i % 8)
+		} else {
+			data[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return data
+}
+
+// Step is one decoded fuzz operation.
+type Step struct {
+	Kind  byte // 0 write, 1 write bytes, 2 align
+	Width uint8
+	Value uint64
+	Data  []byte
+	Pad   Padding
+}
+
+// ParseSteps turns fuzz input into operations, consuming one opcode byte per step.
+func ParseSteps(data []byte) []Step {
+	var steps []Step
+	for len(data) > 0 {
+		step := Step{Kind: data[0] % 3}
+		data = data[1:]
+		switch step.Kind {
+		case 0:
+			var word [9]byte
+			data = data[copy(word[:], data):]
+			step.Width = word[0]%64 + 1
+			step.Value = binary.LittleEndian.Uint64(word[1:])
+		case 1:
+			n := 0
+			if len(data) > 0 {
+				n = int(data[0] % 8)
+				data = data[1:]
+			}
+			step.Data = data[:min(n, len(data))]
+			data = data[len(step.Data):]
+		case 2:
+			if len(data) > 0 {
+				step.Pad = Padding(data[0] % 3)
+				data = data[1:]
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// Replay applies the steps to a writer and the model.
+func Replay(t *testing.T, w *Codec, steps []Step) Reference {
+	var model Reference
+	for _, step := range steps {
+		var err error
+		switch step.Kind {
+		case 0:
+			err = w.Write(step.Width, step.Value)
+			model = model.Append(step.Width, step.Value, w.Order())
+		case 1:
+			err = w.WriteBytes(step.Data)
+			for _, b := range step.Data {
+				model = model.Append(8, uint64(b), w.Order())
+			}
+		case 2:
+			length := uint8((8 - len(model)%8) % 8)
+			err = w.AlignWrite(step.Pad)
+			switch step.Pad {
+			case PadOnes:
+				model = model.Append(length, ^uint64(0), MSBFirst)
+			case PadStuffing:
+				if length == 0 {
+					length = 8
+				}
+				model = model.Append(1, 1, MSBFirst)
+				model = model.Append(length-1, 0, MSBFirst)
+			default:
+				model = model.Append(length, 0, MSBFirst)
+			}
+		}
+		if err != nil {
+			t.Fatalf("step %+v: %v", step, err)
+		}
+	}
+	return model
+}
+
+// Verify reads the steps back from r, checking every value against the model.
+func Verify(t *testing.T, r *Codec, steps []Step, model Reference) {
+	position := 0
+	for _, step := range steps {
+		switch step.Kind {
+		case 0:
+			want := model.Value(position, step.Width, r.Order())
+			if peek, err := r.Peek(step.Width); err != nil || peek != want {
+				t.Fatalf("peek at %d: got %#x, err: %v, want %#x", position, peek, err, want)
+			}
+			if value, err := r.Read(step.Width); err != nil || value != want {
+				t.Fatalf("read at %d: got %#x, err: %v, want %#x", position, value, err, want)
+			}
+			position = position + int(step.Width)
+		case 1:
+			data, err := r.ReadBytes(len(step.Data))
+			if err != nil || !bytes.Equal(data, step.Data) {
+				t.Fatalf("read bytes at %d: got % X, err: %v, want % X", position, data, err, step.Data)
+			}
+			position = position + 8*len(step.Data)
+		case 2:
+			if err := r.AlignRead(step.Pad); err != nil {
+				t.Fatalf("align at %d: %v", position, err)
+			}
+			position = int(r.Position())
+		}
+		if r.Position() != uint64(position) || r.NumRead() != uint64(position) {
+			t.Fatalf("position: got %d, read %d, want %d", r.Position(), r.NumRead(), position)
+		}
+	}
+}
+
+func FuzzWriteRead(f *testing.F) {
+	f.Add([]byte{0, 63, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, false)
+	f.Add([]byte{0, 2, 5, 0, 0, 0, 0, 0, 0, 0, 1, 3, 1, 2, 3, 2, 2, 0, 64, 1}, true)
+	f.Add([]byte{2, 1, 0, 6, 0x2A, 1, 7, 9, 8, 7, 6, 5, 4, 3, 0, 127, 1}, false)
+	f.Fuzz(func(t *testing.T, data []byte, lsb bool) {
+		order := MSBFirst
+		if lsb {
+			order = LSBFirst
+		}
+		steps := ParseSteps(data)
+		w := CreateWriter(WithBitOrder(order), WithCapacity(1))
+		model := Replay(t, w, steps)
+		want := model.Bytes(order)
+		if w.NumWritten() != uint64(len(model)) {
+			t.Fatalf("bits written: got %d, want %d", w.NumWritten(), len(model))
+		}
+		if got := w.Buff[:len(want)]; !bytes.Equal(got, want) || bytes.ContainsFunc(w.Buff[len(want):], func(r rune) bool { return r != 0 }) {
+			t.Fatalf("buffer mismatch:\n% X\nwant:\n% X", w.Buff, want)
+		}
+
+		var out bytes.Buffer
+		stream := CreateStreamWriter(&out, WithBitOrder(order), WithCapacity(2))
+		Replay(t, stream, steps)
+		stream.Close()
+		if !bytes.Equal(out.Bytes(), want) {
+			t.Fatalf("stream mismatch:\n% X\nwant:\n% X", out.Bytes(), want)
+		}
+
+		Verify(t, CreateReader(want, WithBitOrder(order)), steps, model)
+		Verify(t, CreateStreamReader(iotest.OneByteReader(bytes.NewReader(want)), WithBitOrder(order), WithCapacity(1)), steps, model)
+	})
+}
+
+func FuzzSeekRead(f *testing.F) {
+	f.Add([]byte{0xDE, 0xAD, 0xBE, 0xEF, 0x01, 0x23, 0x45, 0x67, 0x89}, []byte{64, 3, 1, 200, 8})
+	f.Fuzz(func(t *testing.T, data, widths []byte) {
+		for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+			var model Reference
+			for _, b := range data {
+				model = model.Append(8, uint64(b), order)
+			}
+			r := CreateReader(data, WithBitOrder(order))
+			for _, b := range widths {
+				position := int(r.Position())
+				// Widths above 64 seek back to the position they encode within the data
+				if b > 64 {
+					target := uint64(b-64) % uint64(len(model)+1)
+					if err := r.Seek(target); err != nil {
+						t.Fatalf("seek %d: %v", target, err)
+					}
+					continue
+				}
+				num := uint8(b)
+				value, err := r.Read(num)
+				if position+int(num) > len(model) {
+					if err == nil {
+						t.Fatalf("read of %d bits at %d past %d bits succeeded", num, position, len(model))
+					}
+					if int(r.Position()) != position {
+						t.Fatalf("failed read moved position from %d to %d", position, r.Position())
+					}
+					continue
+				}
+				if want := model.Value(position, num, order); err != nil || value != want {
+					t.Fatalf("read %d at %d: got %#x, err: %v, want %#x", num, position, value, err, want)
+				}
+			}
+		}
+	})
+}
+
+func FuzzCodes(f *testing.F) {
+	f.Add(uint64(0), int64(0), uint8(0), uint64(1), uint8(1))
+	f.Add(uint64(math.MaxUint64), int64(math.MinInt64), uint8(63), uint64(math.MaxUint64), uint8(64))
+	f.Fuzz(func(t *testing.T, value uint64, signed int64, k uint8, m uint64, num uint8) {
+		k = k % 64
+		m = max(m, 1)
+		num = num%64 + 1
+		// Bound the unary part of Rice and Golomb codes
+		rice := (value>>k)%(1<<14)<<k | value&(1<<k-1)
+		golomb := (value/m)%(1<<14)*m + value%m
+		// The reference ranges for num bit fields, computed without shifting by num
+		var (
+			low    = -int64(uint64(1) << (num - 1))
+			high   = int64(uint64(1)<<(num-1) - 1)
+			fits   = signed >= low && signed <= high
+			zigzag = uint64(signed<<1) ^ uint64(signed>>63)
+		)
+
+		for _, order := range BitOrders {
+			w := CreateWriter(WithBitOrder(order.order))
+			w.WriteUnary(value % 1000)
+			w.WriteEliasGamma(max(value, 1))
+			w.WriteEliasDelta(max(value, 1))
+			w.WriteExpGolomb(min(value, math.MaxUint64-1))
+			w.WriteSignedExpGolomb(max(signed, math.MinInt64+1))
+			w.WriteRice(rice, k)
+			w.WriteGolomb(golomb, m)
+			if err := w.WriteSigned(num, signed); (err == nil) != fits {
+				t.Fatalf("%s: signed %d in %d bits: err %v, fits %t", order.name, signed, num, err, fits)
+			}
+			if err := w.WriteSignMagnitude(num, signed); (err == nil) != (fits && signed != low) {
+				t.Fatalf("%s: sign-magnitude %d in %d bits: err %v", order.name, signed, num, err)
+			}
+			if err := w.WriteZigZag(num, signed); (err == nil) != (num == 64 || zigzag < uint64(1)<<num) {
+				t.Fatalf("%s: zig-zag %d in %d bits: err %v", order.name, signed, num, err)
+			}
+
+			r := CreateReader(w.Buff, WithBitOrder(order.order))
+			checks := []struct {
+				name string
+				read func() (uint64, error)
+				want uint64
+			}{
+				{"unary", r.ReadUnary, value % 1000},
+				{"gamma", r.ReadEliasGamma, max(value, 1)},
+				{"delta", r.ReadEliasDelta, max(value, 1)},
+				{"exp-golomb", r.ReadExpGolomb, min(value, math.MaxUint64-1)},
+				{"signed exp-golomb", func() (uint64, error) { v, err := r.ReadSignedExpGolomb(); return uint64(v), err }, uint64(max(signed, math.MinInt64+1))},
+				{"rice", func() (uint64, error) { return r.ReadRice(k) }, rice},
+				{"golomb", func() (uint64, error) { return r.ReadGolomb(m) }, golomb},
+			}
+			for _, check := range checks {
+				if got, err := check.read(); err != nil || got != check.want {
+					t.Fatalf("%s: %s: got %d, err: %v, want %d", order.name, check.name, got, err, check.want)
+				}
+			}
+			if !fits {
+				continue
+			}
+			if got, err := r.ReadSigned(num); err != nil || got != signed {
+				t.Fatalf("%s: signed: got %d, err: %v, want %d", order.name, got, err, signed)
+			}
+			if signed != low {
+				if got, err := r.ReadSignMagnitude(num); err != nil || got != signed {
+					t.Fatalf("%s: sign-magnitude: got %d, err: %v, want %d", order.name, got, err, signed)
+				}
+			}
+			if num == 64 || zigzag < uint64(1)<<num {
+				if got, err := r.ReadZigZag(num); err != nil || got != signed {
+					t.Fatalf("%s: zig-zag: got %d, err: %v, want %d", order.name, got, err, signed)
+				}
+			}
+		}
+	})
+}
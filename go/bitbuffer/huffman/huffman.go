@@ -0,0 +1,245 @@
+// Package huffman implements canonical Huffman codes over bitbuffer.Codec, as used by DEFLATE and
+// JPEG entropy coding.
+//
+// Codes are emitted most significant bit first regardless of the bit order of the Codec, so the
+// same Table encodes JPEG style streams on an MSB-first Codec and DEFLATE style streams on an
+// LSB-first Codec.
+package huffman
+
+import (
+	"errors"
+	"math/bits"
+	"slices"
+
+	"playground/go/bitbuffer"
+)
+
+// MaxLength is the longest supported code length in bits.
+const MaxLength = 32
+
+// lookupBits is the width of the primary decoding table, longer codes are decoded canonically.
+const lookupBits = 9
+
+var (
+	// ErrOversubscribed reports code lengths that do not form a prefix code.
+	ErrOversubscribed = errors.New("huffman code lengths are oversubscribed")
+	// ErrNoCode reports a symbol without a code in the table.
+	ErrNoCode = errors.New("symbol has no huffman code")
+	// ErrInvalidCode reports input bits that do not match any code in the table.
+	ErrInvalidCode = errors.New("invalid huffman code")
+	// ErrInvalidLength reports a code length limit outside 1 to MaxLength.
+	ErrInvalidLength = errors.New("invalid huffman code length")
+)
+
+// Code is the canonical code of a symbol, the Length least significant bits of Bits.
+type Code struct {
+	Bits   uint32
+	Length uint8
+}
+
+// entry is a primary decoding table slot, Length zero marks a code longer than lookupBits.
+type entry struct {
+	symbol uint16
+	length uint8
+}
+
+// Table holds a canonical Huffman code for symbols 0 to n-1.
+type Table struct {
+	codes   []Code
+	counts  [MaxLength + 1]uint16 // Number of codes of each length
+	symbols []uint16              // Symbols ordered by code length then value
+	lookup  []entry               // Primary table indexed by the next lookupBits stream bits
+	length  uint8                 // Longest code length
+}
+
+// FromLengths builds the canonical code for the given per-symbol code lengths, zero meaning the
+// symbol has no code. Incomplete codes are accepted, oversubscribed ones are not.
+func FromLengths(lengths []uint8) (*Table, error) {
+	if len(lengths) > 1<<16 {
+		return nil, errors.New("too many huffman symbols")
+	}
+	t := &Table{codes: make([]Code, len(lengths))}
+	for _, length := range lengths {
+		if length > MaxLength {
+			return nil, ErrInvalidLength
+		}
+		t.counts[length]++
+		t.length = max(t.length, length)
+	}
+	t.counts[0] = 0
+
+	// Check the Kraft inequality and compute the first code of each length
+	var (
+		next [MaxLength + 2]uint64
+		left = uint64(1)
+	)
+	for length := 1; length <= MaxLength; length++ {
+		left = left << 1
+		if uint64(t.counts[length]) > left {
+			return nil, ErrOversubscribed
+		}
+		left = left - uint64(t.counts[length])
+		next[length+1] = (next[length] + uint64(t.counts[length])) << 1
+	}
+	for symbol, length := range lengths {
+		if length > 0 {
+			t.codes[symbol] = Code{Bits: uint32(next[length]), Length: length}
+			next[length]++
+		}
+	}
+
+	// Order symbols by length for canonical decoding
+	for length := 1; length <= int(t.length); length++ {
+		for symbol, l := range lengths {
+			if int(l) == length {
+				t.symbols = append(t.symbols, uint16(symbol))
+			}
+		}
+	}
+
+	// Fill the primary table with every code that fits in it
+	t.lookup = make([]entry, 1<<lookupBits)
+	for symbol, code := range t.codes {
+		if code.Length == 0 || code.Length > lookupBits {
+			continue
+		}
+		var (
+			shift = lookupBits - code.Length
+			first = int(code.Bits) << shift
+		)
+		for i := range 1 << shift {
+			t.lookup[first+i] = entry{symbol: uint16(symbol), length: code.Length}
+		}
+	}
+	return t, nil
+}
+
+// FromFrequencies builds an optimal canonical code for the given per-symbol frequencies with no
+// code longer than limit bits, using the package-merge algorithm. Symbols with zero frequency get
+// no code, a single used symbol gets a one bit code.
+func FromFrequencies(frequencies []uint64, limit uint8) (*Table, error) {
+	if limit < 1 || limit > MaxLength {
+		return nil, ErrInvalidLength
+	}
+	type item struct {
+		weight  uint64
+		symbols []int
+	}
+	var leaves []item
+	for symbol, frequency := range frequencies {
+		if frequency > 0 {
+			leaves = append(leaves, item{weight: frequency, symbols: []int{symbol}})
+		}
+	}
+	lengths := make([]uint8, len(frequencies))
+	switch {
+	case len(leaves) == 0:
+		return FromLengths(lengths)
+	case len(leaves) == 1:
+		lengths[leaves[0].symbols[0]] = 1
+		return FromLengths(lengths)
+	case uint64(len(leaves)) > uint64(1)<<limit:
+		return nil, ErrInvalidLength
+	}
+	slices.SortStableFunc(leaves, func(a, b item) int {
+		switch {
+		case a.weight < b.weight:
+			return -1
+		case a.weight > b.weight:
+			return 1
+		}
+		return 0
+	})
+
+	// Repeatedly package pairs of the current list and merge them with the leaves
+	list := leaves
+	for range limit - 1 {
+		packages := make([]item, 0, len(list)/2)
+		for i := 0; i+1 < len(list); i += 2 {
+			symbols := append(slices.Clone(list[i].symbols), list[i+1].symbols...)
+			packages = append(packages, item{weight: list[i].weight + list[i+1].weight, symbols: symbols})
+		}
+		merged := make([]item, 0, len(leaves)+len(packages))
+		i, j := 0, 0
+		for i < len(leaves) || j < len(packages) {
+			if j == len(packages) || (i < len(leaves) && leaves[i].weight <= packages[j].weight) {
+				merged = append(merged, leaves[i])
+				i++
+			} else {
+				merged = append(merged, packages[j])
+				j++
+			}
+		}
+		list = merged
+	}
+	for _, chosen := range list[:2*len(leaves)-2] {
+		for _, symbol := range chosen.symbols {
+			lengths[symbol]++
+		}
+	}
+	return FromLengths(lengths)
+}
+
+// Lengths returns the code length of every symbol.
+func (t *Table) Lengths() []uint8 {
+	lengths := make([]uint8, len(t.codes))
+	for symbol, code := range t.codes {
+		lengths[symbol] = code.Length
+	}
+	return lengths
+}
+
+// Code returns the code of symbol, reporting whether it has one.
+func (t *Table) Code(symbol int) (Code, bool) {
+	if symbol < 0 || symbol >= len(t.codes) || t.codes[symbol].Length == 0 {
+		return Code{}, false
+	}
+	return t.codes[symbol], true
+}
+
+// Encode writes the code of symbol to the bit stream.
+func (t *Table) Encode(w *bitbuffer.Codec, symbol int) error {
+	code, ok := t.Code(symbol)
+	if !ok {
+		return ErrNoCode
+	}
+	value := uint64(code.Bits)
+	if w.Order() == bitbuffer.LSBFirst {
+		value = bits.Reverse64(value) >> (64 - code.Length)
+	}
+	return w.Write(code.Length, value)
+}
+
+// Decode reads the next symbol from the bit stream.
+func (t *Table) Decode(r *bitbuffer.Codec) (int, error) {
+	if peek, err := r.Peek(lookupBits); err == nil {
+		if r.Order() == bitbuffer.LSBFirst {
+			peek = bits.Reverse64(peek) >> (64 - lookupBits)
+		}
+		if e := t.lookup[peek]; e.length > 0 {
+			// Read rather than skip the code so that a traced Codec records the code bits
+			if _, err := r.Read(e.length); err != nil {
+				return 0, err
+			}
+			return int(e.symbol), nil
+		}
+	}
+
+	// Decode long codes and codes near the end of the stream one bit at a time
+	var code, first, index uint64
+	for length := 1; length <= int(t.length); length++ {
+		bit, err := r.Read(1)
+		if err != nil {
+			return 0, err
+		}
+		code = code | bit
+		count := uint64(t.counts[length])
+		if code-first < count {
+			return int(t.symbols[index+code-first]), nil
+		}
+		index = index + count
+		first = (first + count) << 1
+		code = code << 1
+	}
+	return 0, ErrInvalidCode
+}
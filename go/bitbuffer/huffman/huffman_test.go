@@ -0,0 +1,223 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"playground/go/bitbuffer"
+)
+
+func CodeString(code Code) string {
+	return fmt.Sprintf("%0*b", code.Length, code.Bits)
+}
+
+func TestCanonicalCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		lengths []uint8
+		codes   []string
+	}{
+		// RFC 1951 section 3.2.2 example for the alphabet ABCDEFGH
+		{"deflate example", []uint8{3, 3, 3, 3, 3, 2, 4, 4}, []string{"010", "011", "100", "101", "110", "00", "1110", "1111"}},
+		// JPEG Annex K.3 table K.3, luminance DC differences
+		{"jpeg luminance dc", []uint8{2, 3, 3, 3, 3, 3, 4, 5, 6, 7, 8, 9}, []string{
+			"00", "010", "011", "100", "101", "110", "1110", "11110", "111110", "1111110", "11111110", "111111110",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := FromLengths(tt.lengths)
+			if err != nil {
+				t.Fatalf("table error: %v", err)
+			}
+			for symbol, want := range tt.codes {
+				code, ok := table.Code(symbol)
+				if !ok || CodeString(code) != want {
+					t.Errorf("symbol %d: got %s, want %s", symbol, CodeString(code), want)
+				}
+			}
+		})
+	}
+}
+
+// FixedLiteralLengths returns the DEFLATE fixed literal/length code lengths of RFC 1951 section 3.2.6.
+func FixedLiteralLengths() []uint8 {
+	lengths := make([]uint8, 288)
+	for symbol := range lengths {
+		switch {
+		case symbol < 144:
+			lengths[symbol] = 8
+		case symbol < 256:
+			lengths[symbol] = 9
+		case symbol < 280:
+			lengths[symbol] = 7
+		default:
+			lengths[symbol] = 8
+		}
+	}
+	return lengths
+}
+
+func TestDeflateFixedCodes(t *testing.T) {
+	table, err := FromLengths(FixedLiteralLengths())
+	if err != nil {
+		t.Fatalf("table error: %v", err)
+	}
+	writer := bitbuffer.CreateWriter(bitbuffer.WithBitOrder(bitbuffer.LSBFirst))
+	// Fixed block header: BFINAL=1, BTYPE=01, then "A" and end of block
+	writer.Write(1, 1)
+	writer.Write(2, 1)
+	for _, symbol := range []int{'A', 256} {
+		if err := table.Encode(writer, symbol); err != nil {
+			t.Fatalf("encode error: %v", err)
+		}
+	}
+	// Same bytes as zlib produces for a raw deflate of "A"
+	want := []byte{0x73, 0x04, 0x00}
+	if got := writer.Buff[:(writer.NumWritten()+7)/8]; !bytes.Equal(got, want) {
+		t.Errorf("encoded: got % X, want % X", got, want)
+	}
+	reader := bitbuffer.CreateReader(want, bitbuffer.WithBitOrder(bitbuffer.LSBFirst))
+	reader.Read(3)
+	for _, symbol := range []int{'A', 256} {
+		if got, err := table.Decode(reader); err != nil || got != symbol {
+			t.Errorf("decode: got %d, err: %v, want %d", got, err, symbol)
+		}
+	}
+}
+
+func TestDecodeTrace(t *testing.T) {
+	table, err := FromLengths([]uint8{3, 3, 3, 3, 3, 2, 4, 4})
+	if err != nil {
+		t.Fatalf("table error: %v", err)
+	}
+	symbols := []int{5, 6, 0}
+	writer := bitbuffer.CreateWriter()
+	for _, symbol := range symbols {
+		table.Encode(writer, symbol)
+	}
+	reader := bitbuffer.CreateReader(writer.Buff, bitbuffer.WithTrace())
+	for _, symbol := range symbols {
+		if got, err := table.Decode(reader); err != nil || got != symbol {
+			t.Fatalf("decode: got %d, err: %v, want %d", got, err, symbol)
+		}
+	}
+	events := reader.Trace().Events
+	if len(events) != len(symbols) {
+		t.Fatalf("got %d events, want %d", len(events), len(symbols))
+	}
+	for i, symbol := range symbols {
+		code, _ := table.Code(symbol)
+		if e := events[i]; e.Op != "read" || e.Width != uint64(code.Length) || e.Value != uint64(code.Bits) {
+			t.Errorf("symbol %d: got %s of %d bits = %b, want code %s", symbol, e.Op, e.Width, e.Value, CodeString(code))
+		}
+	}
+}
+
+func TestFrequencyRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, limit := range []uint8{9, 12, 15} {
+		for _, order := range []bitbuffer.BitOrder{bitbuffer.MSBFirst, bitbuffer.LSBFirst} {
+			// Skewed frequencies produce codes longer than the limit without package-merge
+			frequencies := make([]uint64, 300)
+			for symbol := range frequencies {
+				if rng.Intn(10) > 0 {
+					frequencies[symbol] = 1 << uint(rng.Intn(30))
+				}
+			}
+			table, err := FromFrequencies(frequencies, limit)
+			if err != nil {
+				t.Fatalf("table error: %v", err)
+			}
+			var kraft float64
+			for symbol, length := range table.Lengths() {
+				if (length > 0) != (frequencies[symbol] > 0) {
+					t.Errorf("symbol %d: frequency %d, length %d", symbol, frequencies[symbol], length)
+				}
+				if length > limit {
+					t.Errorf("symbol %d: length %d exceeds limit %d", symbol, length, limit)
+				}
+				if length > 0 {
+					kraft = kraft + 1/float64(uint64(1)<<length)
+				}
+			}
+			if kraft != 1 {
+				t.Errorf("limit %d: code is not complete, kraft sum %f", limit, kraft)
+			}
+
+			var symbols []int
+			for range 5000 {
+				if symbol := rng.Intn(len(frequencies)); frequencies[symbol] > 0 {
+					symbols = append(symbols, symbol)
+				}
+			}
+			writer := bitbuffer.CreateWriter(bitbuffer.WithBitOrder(order))
+			for _, symbol := range symbols {
+				if err := table.Encode(writer, symbol); err != nil {
+					t.Fatalf("encode error: %v", err)
+				}
+			}
+			reader := bitbuffer.CreateReader(writer.Buff[:(writer.NumWritten()+7)/8], bitbuffer.WithBitOrder(order))
+			for i, symbol := range symbols {
+				got, err := table.Decode(reader)
+				if err != nil || got != symbol {
+					t.Fatalf("limit %d, order %d, symbol %d: got %d, err: %v, want %d", limit, order, i, got, err, symbol)
+				}
+			}
+			if reader.NumRead() != writer.NumWritten() {
+				t.Errorf("unexpected bits read: got %d, want %d", reader.NumRead(), writer.NumWritten())
+			}
+		}
+	}
+}
+
+func TestOptimalLengths(t *testing.T) {
+	table, err := FromFrequencies([]uint64{1, 1, 2, 4, 8, 0}, 15)
+	if err != nil {
+		t.Fatalf("table error: %v", err)
+	}
+	if got, want := table.Lengths(), []uint8{4, 4, 3, 2, 1, 0}; !bytes.Equal(got, want) {
+		t.Errorf("unlimited lengths: got %v, want %v", got, want)
+	}
+	table, err = FromFrequencies([]uint64{1, 1, 2, 4, 8, 0}, 3)
+	if err != nil {
+		t.Fatalf("table error: %v", err)
+	}
+	if got, want := table.Lengths(), []uint8{3, 3, 3, 3, 1, 0}; !bytes.Equal(got, want) {
+		t.Errorf("limited lengths: got %v, want %v", got, want)
+	}
+	table, err = FromFrequencies([]uint64{0, 7}, 15)
+	if err != nil {
+		t.Fatalf("table error: %v", err)
+	}
+	if got, want := table.Lengths(), []uint8{0, 1}; !bytes.Equal(got, want) {
+		t.Errorf("single symbol lengths: got %v, want %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := FromLengths([]uint8{1, 1, 1}); !errors.Is(err, ErrOversubscribed) {
+		t.Errorf("expected ErrOversubscribed, got %v", err)
+	}
+	if _, err := FromFrequencies([]uint64{1, 1, 1, 1, 1}, 2); !errors.Is(err, ErrInvalidLength) {
+		t.Errorf("expected ErrInvalidLength, got %v", err)
+	}
+	table, err := FromLengths([]uint8{1, 0, 2})
+	if err != nil {
+		t.Fatalf("table error: %v", err)
+	}
+	if err := table.Encode(bitbuffer.CreateWriter(), 1); !errors.Is(err, ErrNoCode) {
+		t.Errorf("expected ErrNoCode, got %v", err)
+	}
+	// The incomplete code has no entry for 11
+	if _, err := table.Decode(bitbuffer.CreateReader([]byte{0xC0})); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("expected ErrInvalidCode, got %v", err)
+	}
+	if _, err := table.Decode(bitbuffer.CreateReader(nil)); !errors.Is(err, bitbuffer.ErrNotEnoughBits) {
+		t.Errorf("expected ErrNotEnoughBits, got %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package bitbuffer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxSliceLength bounds the length of a decoded slice so that a corrupt length field cannot
+// exhaust memory.
+const maxSliceLength = 1 << 24
+
+// Marshal encodes the struct v as a bit-packed byte slice driven by `bits` struct tags.
+//
+// The tag value is a field width optionally followed by comma separated options:
+//
+//	Version uint8    `bits:"4"`           // 4 bit unsigned integer
+//	Delta   int16    `bits:"10"`          // 10 bit two's complement integer
+//	Flag    bool                          // 1 bit, widths default to the size of the type
+//	Items   []uint8  `bits:"6,len=5"`     // 5 bit length prefix followed by 6 bit elements
+//	Words   []uint16 `bits:",len=Count"`  // length taken from the earlier field Count
+//	Extra   uint32   `bits:"24,if=Flag"`  // present only when Flag is non-zero
+//	Kind    uint8    `bits:"3,if=Version==2"`
+//	Ignored int      `bits:"-"`
+//
+// Conditions support `Name`, `Name==value` and `Name!=value` referencing earlier fields of the
+// same struct. Fixed arrays and nested structs are encoded element by element, unexported fields
+// are skipped. The final byte is zero padded.
+func Marshal(v any) ([]byte, error) {
+	writer := CreateWriter()
+	if err := writer.Encode(v); err != nil {
+		return nil, err
+	}
+	return writer.Buff[:(writer.NumWritten()+7)/8], nil
+}
+
+// Unmarshal decodes the bit-packed data into the struct pointed to by v, see Marshal for the tag format.
+func Unmarshal(data []byte, v any) error {
+	return CreateReader(data).Decode(v)
+}
+
+// Encode writes the struct v to the bit stream, see Marshal for the tag format.
+func (w *Codec) Encode(v any) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return errors.New("cannot encode nil pointer")
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot encode %s, expected struct", value.Type())
+	}
+	return w.encodeStruct(value)
+}
+
+// Decode reads the struct pointed to by v from the bit stream, see Marshal for the tag format.
+func (w *Codec) Decode(v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return errors.New("decode requires a non-nil pointer")
+	}
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode %s, expected struct", value.Type())
+	}
+	return w.decodeStruct(value)
+}
+
+// fieldSpec holds the parsed `bits` tag of a struct field.
+type fieldSpec struct {
+	bits   uint8  // Field or element width, 0 for the type default
+	length string // Length prefix width or name of the field holding the length
+	cond   string // Condition on an earlier field
+}
+
+// parseSpec parses the `bits` tag of a struct field, reporting whether the field is skipped.
+func parseSpec(field reflect.StructField) (fieldSpec, bool, error) {
+	var spec fieldSpec
+	if !field.IsExported() {
+		return spec, true, nil
+	}
+	tag := field.Tag.Get("bits")
+	if tag == "-" {
+		return spec, true, nil
+	}
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		bits, err := strconv.ParseUint(parts[0], 10, 8)
+		if err != nil || bits < 1 || bits > 64 {
+			return spec, false, fmt.Errorf("invalid width %q", parts[0])
+		}
+		spec.bits = uint8(bits)
+	}
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return spec, false, fmt.Errorf("invalid option %q", part)
+		}
+		switch key {
+		case "len":
+			spec.length = value
+		case "if":
+			spec.cond = value
+		default:
+			return spec, false, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	return spec, false, nil
+}
+
+// reference returns the integer value of the field name declared before index in the struct.
+func reference(parent reflect.Value, index int, name string) (uint64, error) {
+	field, ok := parent.Type().FieldByName(name)
+	if !ok || len(field.Index) != 1 || field.Index[0] >= index {
+		return 0, fmt.Errorf("unknown earlier field %q", name)
+	}
+	value := parent.Field(field.Index[0])
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(value.Int()), nil
+	}
+	return 0, fmt.Errorf("field %q is not an integer", name)
+}
+
+// present evaluates the condition of a field against the earlier fields of the struct.
+func present(parent reflect.Value, index int, cond string) (bool, error) {
+	if cond == "" {
+		return true, nil
+	}
+	name, operand, equal := cond, "", true
+	if left, right, ok := strings.Cut(cond, "=="); ok {
+		name, operand = left, right
+	} else if left, right, ok := strings.Cut(cond, "!="); ok {
+		name, operand, equal = left, right, false
+	}
+	value, err := reference(parent, index, name)
+	if err != nil {
+		return false, err
+	}
+	if operand == "" {
+		return value != 0, nil
+	}
+	expect, err := strconv.ParseInt(operand, 0, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q", cond)
+	}
+	return (value == uint64(expect)) == equal, nil
+}
+
+// width returns the encoded width of a scalar of type t with the requested width.
+func width(t reflect.Type, bits uint8) (uint8, error) {
+	size := uint8(1)
+	if t.Kind() != reflect.Bool {
+		size = uint8(t.Bits())
+	}
+	if bits == 0 {
+		return size, nil
+	}
+	if bits > size {
+		return 0, fmt.Errorf("width %d exceeds %s", bits, t)
+	}
+	return bits, nil
+}
+
+// minWidth returns the fewest bits a value of type t can be decoded from, 0 when it may take none
+// such as a struct whose fields are all skipped or conditional.
+func minWidth(t reflect.Type, bits uint8) uint64 {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, err := width(t, bits)
+		if err != nil {
+			return 0
+		}
+		return uint64(num)
+	case reflect.Array:
+		return uint64(t.Len()) * minWidth(t.Elem(), bits)
+	case reflect.Struct:
+		var total uint64
+		for i := 0; i < t.NumField(); i++ {
+			spec, skip, err := parseSpec(t.Field(i))
+			if err != nil || skip || spec.cond != "" {
+				continue
+			}
+			if t.Field(i).Type.Kind() != reflect.Slice {
+				total = total + minWidth(t.Field(i).Type, spec.bits)
+			} else if prefix, err := strconv.ParseUint(spec.length, 10, 8); err == nil {
+				total = total + prefix
+			}
+		}
+		return total
+	}
+	return 0
+}
+
+func (w *Codec) encodeStruct(value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		spec, skip, err := parseSpec(field)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if skip {
+			continue
+		}
+		if err := w.Label(field.Name).encodeField(value, i, spec); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	w.Label("")
+	return nil
+}
+
+func (w *Codec) encodeField(parent reflect.Value, index int, spec fieldSpec) error {
+	ok, err := present(parent, index, spec.cond)
+	if err != nil || !ok {
+		return err
+	}
+	value := parent.Field(index)
+	if value.Kind() != reflect.Slice {
+		if spec.length != "" {
+			return errors.New("len option requires a slice")
+		}
+		return w.encodeValue(value, spec.bits)
+	}
+	if prefix, err := strconv.ParseUint(spec.length, 10, 8); err == nil {
+		if prefix < 1 || prefix > 64 || (prefix < 64 && uint64(value.Len())>>prefix != 0) {
+			return fmt.Errorf("%w: length %d does not fit in %d bits", ErrRange, value.Len(), prefix)
+		}
+		if err := w.Write(uint8(prefix), uint64(value.Len())); err != nil {
+			return err
+		}
+	} else if spec.length != "" {
+		length, err := reference(parent, index, spec.length)
+		if err != nil {
+			return err
+		}
+		if length != uint64(value.Len()) {
+			return fmt.Errorf("length %d does not match %s=%d", value.Len(), spec.length, length)
+		}
+	} else {
+		return errors.New("slice requires a len option")
+	}
+	for i := 0; i < value.Len(); i++ {
+		if err := w.encodeValue(value.Index(i), spec.bits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Codec) encodeValue(value reflect.Value, bits uint8) error {
+	switch value.Kind() {
+	case reflect.Bool:
+		if _, err := width(value.Type(), bits); err != nil {
+			return err
+		}
+		var bit uint64
+		if value.Bool() {
+			bit = 1
+		}
+		return w.Write(max(bits, 1), bit)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		num, err := width(value.Type(), bits)
+		if err != nil {
+			return err
+		}
+		if num < 64 && value.Uint()>>num != 0 {
+			return fmt.Errorf("%w: %d does not fit in %d bits", ErrRange, value.Uint(), num)
+		}
+		return w.Write(num, value.Uint())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, err := width(value.Type(), bits)
+		if err != nil {
+			return err
+		}
+		return w.WriteSigned(num, value.Int())
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := w.encodeValue(value.Index(i), bits); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		return w.encodeStruct(value)
+	}
+	return fmt.Errorf("unsupported type %s", value.Type())
+}
+
+func (w *Codec) decodeStruct(value reflect.Value) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		spec, skip, err := parseSpec(field)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if skip {
+			continue
+		}
+		if err := w.Label(field.Name).decodeField(value, i, spec); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	w.Label("")
+	return nil
+}
+
+func (w *Codec) decodeField(parent reflect.Value, index int, spec fieldSpec) error {
+	ok, err := present(parent, index, spec.cond)
+	if err != nil {
+		return err
+	}
+	value := parent.Field(index)
+	if !ok {
+		value.SetZero()
+		return nil
+	}
+	if value.Kind() != reflect.Slice {
+		if spec.length != "" {
+			return errors.New("len option requires a slice")
+		}
+		return w.decodeValue(value, spec.bits)
+	}
+	var length uint64
+	if prefix, err := strconv.ParseUint(spec.length, 10, 8); err == nil {
+		if prefix < 1 || prefix > 64 {
+			return fmt.Errorf("invalid length width %d", prefix)
+		}
+		if length, err = w.Read(uint8(prefix)); err != nil {
+			return err
+		}
+	} else if spec.length != "" {
+		if length, err = reference(parent, index, spec.length); err != nil {
+			return err
+		}
+	} else {
+		return errors.New("slice requires a len option")
+	}
+	if length > math.MaxInt || length > maxSliceLength {
+		return w.readError("read", length, ErrRange)
+	}
+	if size := minWidth(value.Type().Elem(), spec.bits); size > 0 && w.src == nil && length > uint64(w.available())/size {
+		return w.readError("read", length, ErrNotEnoughBits)
+	}
+	// Grow the slice as elements are read, a streaming reader may run out of data long before
+	// reaching the length
+	slice := reflect.MakeSlice(value.Type(), 0, int(min(length, InitialBufferSize)))
+	for range length {
+		element := reflect.New(value.Type().Elem()).Elem()
+		if err := w.decodeValue(element, spec.bits); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, element)
+	}
+	value.Set(slice)
+	return nil
+}
+
+func (w *Codec) decodeValue(value reflect.Value, bits uint8) error {
+	switch value.Kind() {
+	case reflect.Bool:
+		if _, err := width(value.Type(), bits); err != nil {
+			return err
+		}
+		bit, err := w.Read(max(bits, 1))
+		if err != nil {
+			return err
+		}
+		value.SetBool(bit != 0)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		num, err := width(value.Type(), bits)
+		if err != nil {
+			return err
+		}
+		result, err := w.Read(num)
+		if err != nil {
+			return err
+		}
+		value.SetUint(result)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, err := width(value.Type(), bits)
+		if err != nil {
+			return err
+		}
+		result, err := w.ReadSigned(num)
+		if err != nil {
+			return err
+		}
+		value.SetInt(result)
+		return nil
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := w.decodeValue(value.Index(i), bits); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		return w.decodeStruct(value)
+	}
+	return fmt.Errorf("unsupported type %s", value.Type())
+}
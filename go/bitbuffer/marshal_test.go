@@ -0,0 +1,186 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type IPv4Header struct {
+	Version  uint8 `bits:"4"`
+	IHL      uint8 `bits:"4"`
+	TOS      uint8
+	Length   uint16
+	ID       uint16
+	Flags    uint8  `bits:"3"`
+	Fragment uint16 `bits:"13"`
+	TTL      uint8
+	Protocol uint8
+	Checksum uint16
+	Source   [4]byte
+	Dest     [4]byte
+}
+
+type Extension struct {
+	Kind  uint8 `bits:"3"`
+	Value int16 `bits:"10"`
+}
+
+type Packet struct {
+	Marker   bool
+	Type     uint8 `bits:"2"`
+	Offset   int8  `bits:"5"`
+	HasExtra bool
+	Extra    Extension   `bits:",if=HasExtra"`
+	Special  uint32      `bits:"20,if=Type==3"`
+	Other    uint8       `bits:"4,if=Type!=3"`
+	Items    []uint8     `bits:"6,len=4"`
+	Count    uint8       `bits:"3"`
+	Words    []Extension `bits:",len=Count"`
+	Ignored  int         `bits:"-"`
+	internal int
+}
+
+func TestMarshalIPv4Header(t *testing.T) {
+	header := IPv4Header{
+		Version:  4,
+		IHL:      5,
+		Length:   0x0054,
+		ID:       0x1C46,
+		Flags:    2,
+		TTL:      64,
+		Protocol: 1,
+		Checksum: 0xB1E6,
+		Source:   [4]byte{192, 168, 0, 1},
+		Dest:     [4]byte{192, 168, 0, 199},
+	}
+	want := []byte{
+		0x45, 0x00, 0x00, 0x54, 0x1C, 0x46, 0x40, 0x00, 0x40, 0x01,
+		0xB1, 0xE6, 0xC0, 0xA8, 0x00, 0x01, 0xC0, 0xA8, 0x00, 0xC7,
+	}
+	data, err := Marshal(header)
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("encoded: got % X, want % X", data, want)
+	}
+	var decoded IPv4Header
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if decoded != header {
+		t.Errorf("mismatch: wrote %+v, read %+v", header, decoded)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	packets := []Packet{
+		{Marker: true, Type: 3, Offset: -16, Special: 0xFFFFF, Items: []uint8{}, Words: []Extension{}},
+		{
+			Type:     1,
+			Offset:   15,
+			HasExtra: true,
+			Extra:    Extension{Kind: 5, Value: -512},
+			Other:    9,
+			Items:    []uint8{1, 63, 20},
+			Count:    2,
+			Words:    []Extension{{1, 511}, {7, -1}},
+		},
+	}
+	for _, packet := range packets {
+		data, err := Marshal(&packet)
+		if err != nil {
+			t.Fatalf("marshal error: %v", err)
+		}
+		var decoded Packet
+		decoded.Other = 1
+		if err := Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, packet) {
+			t.Errorf("mismatch: wrote %+v, read %+v", packet, decoded)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"unsigned overflow", struct {
+			A uint8 `bits:"3"`
+		}{8}},
+		{"signed overflow", struct {
+			A int8 `bits:"3"`
+		}{-5}},
+		{"width exceeds type", struct {
+			A uint8 `bits:"9"`
+		}{}},
+		{"invalid width", struct {
+			A uint8 `bits:"x"`
+		}{}},
+		{"slice without len", struct {
+			A []uint8
+		}{}},
+		{"length mismatch", struct {
+			N uint8
+			A []uint8 `bits:",len=N"`
+		}{1, nil}},
+		{"later reference", struct {
+			A uint8 `bits:",if=B"`
+			B uint8
+		}{}},
+		{"unsupported type", struct {
+			A string
+		}{}},
+		{"not a struct", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Marshal(tt.value); err == nil {
+				t.Errorf("expected marshal error")
+			}
+		})
+	}
+	var header IPv4Header
+	if err := Unmarshal([]byte{0x45, 0x00}, &header); err == nil {
+		t.Errorf("expected error unmarshaling truncated data")
+	}
+	if err := Unmarshal([]byte{0x45}, header); err == nil {
+		t.Errorf("expected error unmarshaling into non-pointer")
+	}
+
+	// Corrupt length prefixes must fail without allocating the claimed length
+	var huge struct {
+		A []uint8 `bits:",len=64"`
+	}
+	if err := Unmarshal(bytes.Repeat([]byte{0xFF}, 9), &huge); !errors.Is(err, ErrRange) {
+		t.Errorf("expected ErrRange for an oversized length, got %v", err)
+	}
+	// Elements taking no bits are not limited by the bits left
+	var empty struct {
+		A []struct {
+			Flag  bool  `bits:"-"`
+			Extra uint8 `bits:",if=Flag"`
+		} `bits:",len=8"`
+	}
+	if err := Unmarshal([]byte{200}, &empty); err != nil || len(empty.A) != 200 {
+		t.Errorf("zero width elements: got %d, err: %v", len(empty.A), err)
+	}
+	var wide struct {
+		A []uint16 `bits:",len=8"`
+	}
+	if err := Unmarshal([]byte{2, 0, 1, 0}, &wide); !errors.Is(err, ErrNotEnoughBits) {
+		t.Errorf("expected ErrNotEnoughBits for 16 bit elements, got %v", err)
+	}
+	var long struct {
+		A []uint8 `bits:",len=24"`
+	}
+	r := CreateStreamReader(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 1, 2}))
+	if err := r.Decode(&long); !errors.Is(err, ErrNotEnoughBits) {
+		t.Errorf("expected ErrNotEnoughBits for a truncated stream, got %v", err)
+	}
+}
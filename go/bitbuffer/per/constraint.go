@@ -0,0 +1,94 @@
+// Package per implements the ASN.1 Packed Encoding Rules (ITU-T X.691), in both the ALIGNED and
+// UNALIGNED variants, on top of bitbuffer.Codec.
+//
+// The package provides the building blocks generated or hand written codecs are made of: integers,
+// length determinants, bit and octet strings, enumerations, CHOICE indexes, SEQUENCE preambles and
+// open types. Callers encode the components of a constructed type in order.
+package per
+
+import "errors"
+
+// Bounds classifies the PER-visible bounds of a Constraint.
+type Bounds uint8
+
+const (
+	// Unbounded has neither a lower nor an upper bound.
+	Unbounded Bounds = iota
+	// LowerBounded has only a lower bound, a semi-constrained value.
+	LowerBounded
+	// Bounded has both a lower and an upper bound.
+	Bounded
+)
+
+// Constraint is a PER-visible value constraint on an INTEGER, or a size constraint on a string or list.
+type Constraint struct {
+	Bounds     Bounds
+	Lower      int64
+	Upper      int64
+	Extensible bool // The constraint has an extension marker
+}
+
+// fragment is the number of units in a 16K length fragment (X.691 11.9).
+const fragment = 16384
+
+var (
+	errOutOfRange = errors.New("value outside constraint")
+	errIndex      = errors.New("index out of range")
+)
+
+// Constrained creates a constraint with lower bound lb and upper bound ub.
+func Constrained(lb, ub int64) Constraint {
+	return Constraint{Bounds: Bounded, Lower: lb, Upper: ub}
+}
+
+// SemiConstrained creates a constraint with only a lower bound lb.
+func SemiConstrained(lb int64) Constraint {
+	return Constraint{Bounds: LowerBounded, Lower: lb}
+}
+
+// Unconstrained creates a constraint without bounds.
+func Unconstrained() Constraint {
+	return Constraint{Bounds: Unbounded}
+}
+
+// Size creates a size constraint from lb to ub, Unsized for no size constraint.
+func Size(lb, ub int64) Constraint {
+	return Constrained(lb, ub)
+}
+
+// Unsized creates an absent size constraint.
+func Unsized() Constraint {
+	return SemiConstrained(0)
+}
+
+// Extended returns a copy of the constraint with an extension marker.
+func (c Constraint) Extended() Constraint {
+	c.Extensible = true
+	return c
+}
+
+// Contains reports whether value lies within the root of the constraint.
+func (c Constraint) Contains(value int64) bool {
+	switch c.Bounds {
+	case Bounded:
+		return value >= c.Lower && value <= c.Upper
+	case LowerBounded:
+		return value >= c.Lower
+	}
+	return true
+}
+
+// fixed reports whether the constraint allows exactly one size no larger than limit.
+func (c Constraint) fixed(limit int64) bool {
+	return c.Bounds == Bounded && c.Lower == c.Upper && c.Upper <= limit
+}
+
+// small reports whether a length under the constraint is encoded as a constrained whole number.
+func (c Constraint) small() bool {
+	return c.Bounds == Bounded && c.Upper < 65536
+}
+
+// span returns the range of the constraint minus one.
+func (c Constraint) span() uint64 {
+	return uint64(c.Upper) - uint64(c.Lower)
+}
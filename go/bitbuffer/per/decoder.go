@@ -0,0 +1,435 @@
+package per
+
+import (
+	"errors"
+	"math/bits"
+
+	"playground/go/bitbuffer"
+)
+
+// Decoder reads PER encodings from a bit stream.
+type Decoder struct {
+	codec   *bitbuffer.Codec
+	aligned bool
+}
+
+// CreateDecoder creates a new Decoder over data for the ALIGNED variant if aligned is set, UNALIGNED otherwise.
+func CreateDecoder(data []byte, aligned bool) *Decoder {
+	return &Decoder{codec: bitbuffer.CreateReader(data), aligned: aligned}
+}
+
+// Codec returns the underlying bit stream.
+func (d *Decoder) Codec() *bitbuffer.Codec {
+	return d.codec
+}
+
+// Align skips the padding up to the next octet boundary in the ALIGNED variant.
+func (d *Decoder) Align() error {
+	if !d.aligned {
+		return nil
+	}
+	return d.codec.Skip((8 - d.codec.Position()%8) % 8)
+}
+
+// readBit reads a single bit.
+func (d *Decoder) readBit() (bool, error) {
+	bit, err := d.codec.Read(1)
+	return bit == 1, err
+}
+
+// DecodeBoolean decodes a BOOLEAN (X.691 12).
+func (d *Decoder) DecodeBoolean() (bool, error) {
+	return d.readBit()
+}
+
+// decodeConstrained decodes a constrained whole number with the given range minus one, returning
+// the offset from the lower bound (X.691 11.5).
+func (d *Decoder) decodeConstrained(span uint64) (uint64, error) {
+	if span == 0 {
+		return 0, nil
+	}
+	if !d.aligned {
+		return d.codec.Read(uint8(bits.Len64(span)))
+	}
+	switch {
+	case span < 255:
+		return d.codec.Read(uint8(bits.Len64(span)))
+	case span == 255:
+		if err := d.Align(); err != nil {
+			return 0, err
+		}
+		return d.codec.Read(8)
+	case span < 65536:
+		if err := d.Align(); err != nil {
+			return 0, err
+		}
+		return d.codec.Read(16)
+	}
+	limit := (bits.Len64(span) + 7) / 8
+	octets, err := d.decodeConstrained(uint64(limit - 1))
+	if err != nil {
+		return 0, err
+	}
+	if err := d.Align(); err != nil {
+		return 0, err
+	}
+	return d.codec.Read(uint8(8 * (octets + 1)))
+}
+
+// decodeSemiConstrained decodes a semi-constrained whole number, returning the offset from the
+// lower bound (X.691 11.7).
+func (d *Decoder) decodeSemiConstrained() (uint64, error) {
+	octets, err := d.DecodeLength(Unsized())
+	if err != nil {
+		return 0, err
+	}
+	if octets < 1 || octets > 8 {
+		return 0, errors.New("integer length out of range")
+	}
+	return d.codec.Read(uint8(8 * octets))
+}
+
+// decodeUnconstrained decodes an unconstrained whole number in two's complement (X.691 11.8).
+func (d *Decoder) decodeUnconstrained() (int64, error) {
+	octets, err := d.DecodeLength(Unsized())
+	if err != nil {
+		return 0, err
+	}
+	if octets < 1 || octets > 8 {
+		return 0, errors.New("integer length out of range")
+	}
+	value, err := d.codec.Read(uint8(8 * octets))
+	if err != nil {
+		return 0, err
+	}
+	shift := 64 - 8*octets
+	return int64(value<<shift) >> shift, nil
+}
+
+// DecodeInteger decodes an INTEGER under the given value constraint (X.691 13).
+func (d *Decoder) DecodeInteger(c Constraint) (int64, error) {
+	if c.Extensible {
+		extended, err := d.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if extended {
+			return d.decodeUnconstrained()
+		}
+	}
+	switch c.Bounds {
+	case Bounded:
+		offset, err := d.decodeConstrained(c.span())
+		if err != nil {
+			return 0, err
+		}
+		if offset > c.span() {
+			return 0, errOutOfRange
+		}
+		return int64(uint64(c.Lower) + offset), nil
+	case LowerBounded:
+		offset, err := d.decodeSemiConstrained()
+		if err != nil {
+			return 0, err
+		}
+		return int64(uint64(c.Lower) + offset), nil
+	}
+	return d.decodeUnconstrained()
+}
+
+// DecodeNormallySmall decodes a normally small non-negative whole number (X.691 11.6).
+func (d *Decoder) DecodeNormallySmall() (uint64, error) {
+	large, err := d.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if large {
+		return d.decodeSemiConstrained()
+	}
+	return d.codec.Read(6)
+}
+
+// DecodeLength decodes a length determinant under the given size constraint (X.691 11.9). Lengths
+// of 16K or more need fragmentation and are only supported through the string decoders.
+func (d *Decoder) DecodeLength(c Constraint) (int, error) {
+	if c.small() {
+		offset, err := d.decodeConstrained(c.span())
+		if err != nil {
+			return 0, err
+		}
+		if offset > c.span() {
+			return 0, errOutOfRange
+		}
+		return int(c.Lower) + int(offset), nil
+	}
+	length, fragmented, err := d.decodeLength()
+	if err != nil {
+		return 0, err
+	}
+	if fragmented {
+		return 0, errors.New("length requires fragmentation")
+	}
+	return length, nil
+}
+
+// decodeLength decodes an unconstrained length determinant, reporting whether it is a fragment.
+func (d *Decoder) decodeLength() (int, bool, error) {
+	if err := d.Align(); err != nil {
+		return 0, false, err
+	}
+	header, err := d.codec.Read(8)
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case header&0x80 == 0:
+		return int(header), false, nil
+	case header&0xC0 == 0x80:
+		low, err := d.codec.Read(8)
+		if err != nil {
+			return 0, false, err
+		}
+		return int(header&0x3F)<<8 | int(low), false, nil
+	}
+	count := int(header & 0x3F)
+	if count < 1 || count > 4 {
+		return 0, false, errors.New("invalid fragment count")
+	}
+	return count * fragment, true, nil
+}
+
+// decodeNormallySmallLength decodes a normally small length (X.691 11.9).
+func (d *Decoder) decodeNormallySmallLength() (int, error) {
+	large, err := d.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if large {
+		return d.DecodeLength(Unsized())
+	}
+	length, err := d.codec.Read(6)
+	return int(length) + 1, err
+}
+
+// decodeFragments reads a length determinant and the units it covers, reassembling 16K fragments
+// when the length is not a constrained whole number (X.691 11.9).
+func (d *Decoder) decodeFragments(c Constraint, units func(count int) error) (int, error) {
+	if c.small() {
+		length, err := d.DecodeLength(c)
+		if err != nil {
+			return 0, err
+		}
+		return length, units(length)
+	}
+	total := 0
+	for {
+		length, fragmented, err := d.decodeLength()
+		if err != nil {
+			return 0, err
+		}
+		if err := units(length); err != nil {
+			return 0, err
+		}
+		total = total + length
+		if !fragmented {
+			break
+		}
+	}
+	if !c.Contains(int64(total)) {
+		return 0, errOutOfRange
+	}
+	return total, nil
+}
+
+// extension reads the extension bit for a size constraint, returning the effective constraint.
+func (d *Decoder) extension(c Constraint) (Constraint, error) {
+	if !c.Extensible {
+		return c, nil
+	}
+	extended, err := d.readBit()
+	if err != nil || !extended {
+		return c, err
+	}
+	return Unsized(), nil
+}
+
+// readBits reads count bits into a byte slice, left aligned in the final octet.
+func (d *Decoder) readBits(count int) ([]byte, error) {
+	data, err := d.codec.ReadBytes(count / 8)
+	if err != nil {
+		return nil, err
+	}
+	if rest := count % 8; rest > 0 {
+		value, err := d.codec.Read(uint8(rest))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, byte(value<<(8-rest)))
+	}
+	return data, nil
+}
+
+// DecodeBitString decodes a BIT STRING under the given size constraint, returning the bits left
+// aligned in octets and the number of bits (X.691 16).
+func (d *Decoder) DecodeBitString(c Constraint) ([]byte, int, error) {
+	c, err := d.extension(c)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case c.fixed(0):
+		return []byte{}, 0, nil
+	case c.fixed(16):
+		data, err := d.readBits(int(c.Upper))
+		return data, int(c.Upper), err
+	case c.fixed(65535):
+		if err := d.Align(); err != nil {
+			return nil, 0, err
+		}
+		data, err := d.readBits(int(c.Upper))
+		return data, int(c.Upper), err
+	}
+	var data []byte
+	length, err := d.decodeFragments(c, func(count int) error {
+		if count > 0 {
+			if err := d.Align(); err != nil {
+				return err
+			}
+		}
+		chunk, err := d.readBits(count)
+		data = append(data, chunk...)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, length, nil
+}
+
+// DecodeOctetString decodes an OCTET STRING under the given size constraint (X.691 17).
+func (d *Decoder) DecodeOctetString(c Constraint) ([]byte, error) {
+	c, err := d.extension(c)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.fixed(0):
+		return []byte{}, nil
+	case c.fixed(2):
+		return d.codec.ReadBytes(int(c.Upper))
+	case c.fixed(65535):
+		if err := d.Align(); err != nil {
+			return nil, err
+		}
+		return d.codec.ReadBytes(int(c.Upper))
+	}
+	data := []byte{}
+	_, err = d.decodeFragments(c, func(count int) error {
+		if count > 0 {
+			if err := d.Align(); err != nil {
+				return err
+			}
+		}
+		chunk, err := d.codec.ReadBytes(count)
+		data = append(data, chunk...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeIndex decodes an index into root alternatives followed by extension alternatives.
+func (d *Decoder) decodeIndex(root int, extensible bool) (int, error) {
+	if root < 1 {
+		return 0, errIndex
+	}
+	if extensible {
+		extended, err := d.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if extended {
+			index, err := d.DecodeNormallySmall()
+			if err != nil {
+				return 0, err
+			}
+			return root + int(index), nil
+		}
+	}
+	index, err := d.decodeConstrained(uint64(root - 1))
+	if err != nil {
+		return 0, err
+	}
+	if index >= uint64(root) {
+		return 0, errIndex
+	}
+	return int(index), nil
+}
+
+// DecodeEnumerated decodes the index of an ENUMERATED value in sorted order. Indexes from root
+// onwards denote extension values (X.691 14).
+func (d *Decoder) DecodeEnumerated(root int, extensible bool) (int, error) {
+	return d.decodeIndex(root, extensible)
+}
+
+// DecodeChoice decodes the index of the chosen CHOICE alternative in canonical order. Indexes from
+// root onwards denote extension alternatives, whose value follows as an open type (X.691 23).
+func (d *Decoder) DecodeChoice(root int, extensible bool) (int, error) {
+	return d.decodeIndex(root, extensible)
+}
+
+// DecodeSequence decodes the SEQUENCE preamble, returning the extension bit and the presence bitmap
+// of count OPTIONAL and DEFAULT root components (X.691 19).
+func (d *Decoder) DecodeSequence(extensible bool, count int) (bool, []bool, error) {
+	var extended bool
+	if extensible {
+		bit, err := d.readBit()
+		if err != nil {
+			return false, nil, err
+		}
+		extended = bit
+	}
+	optional := make([]bool, count)
+	for i := range optional {
+		bit, err := d.readBit()
+		if err != nil {
+			return false, nil, err
+		}
+		optional[i] = bit
+	}
+	return extended, optional, nil
+}
+
+// DecodeExtensions decodes the presence bitmap of SEQUENCE extension additions, each present
+// addition follows as an open type (X.691 19).
+func (d *Decoder) DecodeExtensions() ([]bool, error) {
+	count, err := d.decodeNormallySmallLength()
+	if err != nil {
+		return nil, err
+	}
+	present := make([]bool, count)
+	for i := range present {
+		bit, err := d.readBit()
+		if err != nil {
+			return nil, err
+		}
+		present[i] = bit
+	}
+	return present, nil
+}
+
+// DecodeOpenType decodes an open type, returning the complete encoding of the contained value (X.691 11.2).
+func (d *Decoder) DecodeOpenType() ([]byte, error) {
+	data := []byte{}
+	_, err := d.decodeFragments(Unsized(), func(count int) error {
+		chunk, err := d.codec.ReadBytes(count)
+		data = append(data, chunk...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,371 @@
+package per
+
+import (
+	"errors"
+	"math/bits"
+
+	"playground/go/bitbuffer"
+)
+
+// Encoder writes PER encodings to a bit stream.
+type Encoder struct {
+	codec   *bitbuffer.Codec
+	aligned bool
+}
+
+// CreateEncoder creates a new Encoder for the ALIGNED variant if aligned is set, UNALIGNED otherwise.
+func CreateEncoder(aligned bool) *Encoder {
+	return &Encoder{codec: bitbuffer.CreateWriter(), aligned: aligned}
+}
+
+// Codec returns the underlying bit stream.
+func (e *Encoder) Codec() *bitbuffer.Codec {
+	return e.codec
+}
+
+// Bytes returns the complete encoding padded to an octet boundary. An empty encoding yields a
+// single zero octet (X.691 11.1).
+func (e *Encoder) Bytes() []byte {
+	size := (e.codec.NumWritten() + 7) / 8
+	if size == 0 {
+		return []byte{0}
+	}
+	return e.codec.Buff[:size]
+}
+
+// Align pads the bit stream to an octet boundary in the ALIGNED variant.
+func (e *Encoder) Align() error {
+	if !e.aligned {
+		return nil
+	}
+	return e.codec.AlignWrite(bitbuffer.PadZeros)
+}
+
+// writeBit writes a single bit.
+func (e *Encoder) writeBit(bit bool) error {
+	if bit {
+		return e.codec.Write(1, 1)
+	}
+	return e.codec.Write(1, 0)
+}
+
+// EncodeBoolean encodes a BOOLEAN (X.691 12).
+func (e *Encoder) EncodeBoolean(value bool) error {
+	return e.writeBit(value)
+}
+
+// encodeConstrained encodes a constrained whole number offset from the lower bound with the given
+// range minus one (X.691 11.5).
+func (e *Encoder) encodeConstrained(offset, span uint64) error {
+	if span == 0 {
+		return nil
+	}
+	if !e.aligned {
+		return e.codec.Write(uint8(bits.Len64(span)), offset)
+	}
+	switch {
+	case span < 255:
+		return e.codec.Write(uint8(bits.Len64(span)), offset)
+	case span == 255:
+		if err := e.Align(); err != nil {
+			return err
+		}
+		return e.codec.Write(8, offset)
+	case span < 65536:
+		if err := e.Align(); err != nil {
+			return err
+		}
+		return e.codec.Write(16, offset)
+	}
+	var (
+		octets = max(1, (bits.Len64(offset)+7)/8)
+		limit  = (bits.Len64(span) + 7) / 8
+	)
+	if err := e.encodeConstrained(uint64(octets-1), uint64(limit-1)); err != nil {
+		return err
+	}
+	if err := e.Align(); err != nil {
+		return err
+	}
+	return e.codec.Write(uint8(8*octets), offset)
+}
+
+// encodeSemiConstrained encodes a semi-constrained whole number offset from the lower bound (X.691 11.7).
+func (e *Encoder) encodeSemiConstrained(offset uint64) error {
+	octets := max(1, (bits.Len64(offset)+7)/8)
+	if err := e.EncodeLength(octets, Unsized()); err != nil {
+		return err
+	}
+	return e.codec.Write(uint8(8*octets), offset)
+}
+
+// encodeUnconstrained encodes an unconstrained whole number in two's complement (X.691 11.8).
+func (e *Encoder) encodeUnconstrained(value int64) error {
+	magnitude := uint64(value)
+	if value < 0 {
+		magnitude = ^magnitude
+	}
+	octets := (bits.Len64(magnitude) + 8) / 8
+	if err := e.EncodeLength(octets, Unsized()); err != nil {
+		return err
+	}
+	return e.codec.Write(uint8(8*octets), uint64(value))
+}
+
+// EncodeInteger encodes an INTEGER under the given value constraint (X.691 13).
+func (e *Encoder) EncodeInteger(value int64, c Constraint) error {
+	if c.Extensible {
+		if !c.Contains(value) {
+			if err := e.writeBit(true); err != nil {
+				return err
+			}
+			return e.encodeUnconstrained(value)
+		}
+		if err := e.writeBit(false); err != nil {
+			return err
+		}
+	} else if !c.Contains(value) {
+		return errOutOfRange
+	}
+	switch c.Bounds {
+	case Bounded:
+		return e.encodeConstrained(uint64(value)-uint64(c.Lower), c.span())
+	case LowerBounded:
+		return e.encodeSemiConstrained(uint64(value) - uint64(c.Lower))
+	}
+	return e.encodeUnconstrained(value)
+}
+
+// EncodeNormallySmall encodes a normally small non-negative whole number (X.691 11.6).
+func (e *Encoder) EncodeNormallySmall(value uint64) error {
+	if value <= 63 {
+		return e.codec.Write(7, value)
+	}
+	if err := e.writeBit(true); err != nil {
+		return err
+	}
+	return e.encodeSemiConstrained(value)
+}
+
+// EncodeLength encodes a length determinant under the given size constraint (X.691 11.9). Lengths
+// of 16K or more need fragmentation and are only supported through the string encoders.
+func (e *Encoder) EncodeLength(length int, c Constraint) error {
+	if length < 0 || !c.Contains(int64(length)) {
+		return errOutOfRange
+	}
+	if c.small() {
+		return e.encodeConstrained(uint64(length)-uint64(c.Lower), c.span())
+	}
+	if err := e.Align(); err != nil {
+		return err
+	}
+	switch {
+	case length < 128:
+		return e.codec.Write(8, uint64(length))
+	case length < fragment:
+		return e.codec.Write(16, 0x8000|uint64(length))
+	}
+	return errors.New("length requires fragmentation")
+}
+
+// encodeNormallySmallLength encodes a normally small length (X.691 11.9).
+func (e *Encoder) encodeNormallySmallLength(length int) error {
+	if length < 1 {
+		return errOutOfRange
+	}
+	if length <= 64 {
+		return e.codec.Write(7, uint64(length-1))
+	}
+	if err := e.writeBit(true); err != nil {
+		return err
+	}
+	return e.EncodeLength(length, Unsized())
+}
+
+// encodeFragments writes a length determinant followed by the units, splitting them into 16K
+// fragments when the length is not a constrained whole number (X.691 11.9).
+func (e *Encoder) encodeFragments(length int, c Constraint, units func(start, count int) error) error {
+	if c.small() {
+		if err := e.EncodeLength(length, c); err != nil {
+			return err
+		}
+		return units(0, length)
+	}
+	if length < 0 || !c.Contains(int64(length)) {
+		return errOutOfRange
+	}
+	start := 0
+	for length-start >= fragment {
+		count := min(4, (length-start)/fragment)
+		if err := e.Align(); err != nil {
+			return err
+		}
+		if err := e.codec.Write(8, 0xC0|uint64(count)); err != nil {
+			return err
+		}
+		if err := units(start, count*fragment); err != nil {
+			return err
+		}
+		start = start + count*fragment
+	}
+	if err := e.EncodeLength(length-start, Unsized()); err != nil {
+		return err
+	}
+	return units(start, length-start)
+}
+
+// extension writes the extension bit for a size constraint, returning the effective constraint.
+func (e *Encoder) extension(length int, c Constraint) (Constraint, error) {
+	if !c.Extensible {
+		if !c.Contains(int64(length)) {
+			return c, errOutOfRange
+		}
+		return c, nil
+	}
+	if c.Contains(int64(length)) {
+		return c, e.writeBit(false)
+	}
+	return Unsized(), e.writeBit(true)
+}
+
+// writeBits writes count bits of data starting at bit start, which is always a multiple of eight.
+func (e *Encoder) writeBits(data []byte, start, count int) error {
+	data = data[start/8:]
+	if err := e.codec.WriteBytes(data[:count/8]); err != nil {
+		return err
+	}
+	if rest := count % 8; rest > 0 {
+		return e.codec.Write(uint8(rest), uint64(data[count/8]>>(8-rest)))
+	}
+	return nil
+}
+
+// EncodeBitString encodes the first length bits of data as a BIT STRING under the given size
+// constraint (X.691 16).
+func (e *Encoder) EncodeBitString(data []byte, length int, c Constraint) error {
+	if length < 0 || length > 8*len(data) {
+		return errors.New("bit length exceeds data")
+	}
+	c, err := e.extension(length, c)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.fixed(0):
+		return nil
+	case c.fixed(16):
+		return e.writeBits(data, 0, length)
+	case c.fixed(65535):
+		if err := e.Align(); err != nil {
+			return err
+		}
+		return e.writeBits(data, 0, length)
+	}
+	return e.encodeFragments(length, c, func(start, count int) error {
+		if count > 0 {
+			if err := e.Align(); err != nil {
+				return err
+			}
+		}
+		return e.writeBits(data, start, count)
+	})
+}
+
+// EncodeOctetString encodes an OCTET STRING under the given size constraint (X.691 17).
+func (e *Encoder) EncodeOctetString(data []byte, c Constraint) error {
+	c, err := e.extension(len(data), c)
+	if err != nil {
+		return err
+	}
+	switch {
+	case c.fixed(0):
+		return nil
+	case c.fixed(2):
+		return e.codec.WriteBytes(data)
+	case c.fixed(65535):
+		if err := e.Align(); err != nil {
+			return err
+		}
+		return e.codec.WriteBytes(data)
+	}
+	return e.encodeFragments(len(data), c, func(start, count int) error {
+		if count > 0 {
+			if err := e.Align(); err != nil {
+				return err
+			}
+		}
+		return e.codec.WriteBytes(data[start : start+count])
+	})
+}
+
+// encodeIndex encodes an index into root alternatives followed by extension alternatives.
+func (e *Encoder) encodeIndex(index, root int, extensible bool) error {
+	if index < 0 || root < 1 {
+		return errIndex
+	}
+	if extensible {
+		if index >= root {
+			if err := e.writeBit(true); err != nil {
+				return err
+			}
+			return e.EncodeNormallySmall(uint64(index - root))
+		}
+		if err := e.writeBit(false); err != nil {
+			return err
+		}
+	} else if index >= root {
+		return errIndex
+	}
+	return e.encodeConstrained(uint64(index), uint64(root-1))
+}
+
+// EncodeEnumerated encodes the index of an ENUMERATED value in sorted order. Indexes from root
+// onwards denote extension values (X.691 14).
+func (e *Encoder) EncodeEnumerated(index, root int, extensible bool) error {
+	return e.encodeIndex(index, root, extensible)
+}
+
+// EncodeChoice encodes the index of the chosen CHOICE alternative in canonical order. Indexes from
+// root onwards denote extension alternatives, whose value must follow as an open type (X.691 23).
+func (e *Encoder) EncodeChoice(index, root int, extensible bool) error {
+	return e.encodeIndex(index, root, extensible)
+}
+
+// EncodeSequence encodes the SEQUENCE preamble: the extension bit if extensible and the presence
+// bitmap of OPTIONAL and DEFAULT root components (X.691 19).
+func (e *Encoder) EncodeSequence(extensible, extended bool, optional []bool) error {
+	if extensible {
+		if err := e.writeBit(extended); err != nil {
+			return err
+		}
+	} else if extended {
+		return errors.New("extension in non-extensible sequence")
+	}
+	for _, present := range optional {
+		if err := e.writeBit(present); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeExtensions encodes the presence bitmap of SEQUENCE extension additions, each present
+// addition must follow as an open type (X.691 19).
+func (e *Encoder) EncodeExtensions(present []bool) error {
+	if err := e.encodeNormallySmallLength(len(present)); err != nil {
+		return err
+	}
+	for _, bit := range present {
+		if err := e.writeBit(bit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeOpenType encodes the complete encoding of a value as an open type (X.691 11.2).
+func (e *Encoder) EncodeOpenType(data []byte) error {
+	return e.encodeFragments(len(data), Unsized(), func(start, count int) error {
+		return e.codec.WriteBytes(data[start : start+count])
+	})
+}
@@ -0,0 +1,380 @@
+package per
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Case encodes a value and decodes it back for comparison.
+type Case struct {
+	Encode func(*Encoder) error
+	Decode func(*Decoder) (any, error)
+	Value  any
+}
+
+// Vector is an encoding under both variants, with expected octets derived from the X.691 clauses.
+type Vector struct {
+	Name      string
+	Case      Case
+	Aligned   string
+	Unaligned string
+}
+
+func Integer(value int64, c Constraint) Case {
+	return Case{
+		Encode: func(e *Encoder) error { return e.EncodeInteger(value, c) },
+		Decode: func(d *Decoder) (any, error) { return d.DecodeInteger(c) },
+		Value:  value,
+	}
+}
+
+func Octets(data []byte, c Constraint) Case {
+	return Case{
+		Encode: func(e *Encoder) error { return e.EncodeOctetString(data, c) },
+		Decode: func(d *Decoder) (any, error) { return d.DecodeOctetString(c) },
+		Value:  data,
+	}
+}
+
+func Bits(data []byte, length int, c Constraint) Case {
+	return Case{
+		Encode: func(e *Encoder) error { return e.EncodeBitString(data, length, c) },
+		Decode: func(d *Decoder) (any, error) {
+			data, length, err := d.DecodeBitString(c)
+			return fmt.Sprintf("%X/%d", data, length), err
+		},
+		Value: fmt.Sprintf("%X/%d", data, length),
+	}
+}
+
+// Leading prefixes the case with a single true BOOLEAN to exercise alignment.
+func Leading(inner Case) Case {
+	return Case{
+		Encode: func(e *Encoder) error {
+			if err := e.EncodeBoolean(true); err != nil {
+				return err
+			}
+			return inner.Encode(e)
+		},
+		Decode: func(d *Decoder) (any, error) {
+			if _, err := d.DecodeBoolean(); err != nil {
+				return nil, err
+			}
+			return inner.Decode(d)
+		},
+		Value: inner.Value,
+	}
+}
+
+// Visible encodes an unconstrained VisibleString, its characters taking 8 bits in the ALIGNED
+// variant and 7 in the UNALIGNED one (X.691 30.5.3).
+func Visible(value string) Case {
+	return Case{
+		Encode: func(e *Encoder) error {
+			if err := e.EncodeLength(len(value), Unsized()); err != nil {
+				return err
+			}
+			for _, c := range []byte(value) {
+				if err := e.codec.Write(charBits(e.aligned), uint64(c)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Decode: func(d *Decoder) (any, error) {
+			length, err := d.DecodeLength(Unsized())
+			if err != nil {
+				return nil, err
+			}
+			value := make([]byte, length)
+			for i := range value {
+				c, err := d.codec.Read(charBits(d.aligned))
+				if err != nil {
+					return nil, err
+				}
+				value[i] = byte(c)
+			}
+			return string(value), nil
+		},
+		Value: value,
+	}
+}
+
+func charBits(aligned bool) uint8 {
+	if aligned {
+		return 8
+	}
+	return 7
+}
+
+// Count encodes the number of components of an unconstrained SEQUENCE OF.
+func Count(length int) Case {
+	return Case{
+		Encode: func(e *Encoder) error { return e.EncodeLength(length, Unsized()) },
+		Decode: func(d *Decoder) (any, error) { return d.DecodeLength(Unsized()) },
+		Value:  length,
+	}
+}
+
+// Record encodes a SEQUENCE or SET with the presence bitmap of its OPTIONAL and DEFAULT components
+// followed by the components in order.
+func Record(optional []bool, components ...Case) Case {
+	values := make([]any, len(components))
+	for i, c := range components {
+		values[i] = c.Value
+	}
+	return Case{
+		Encode: func(e *Encoder) error {
+			if err := e.EncodeSequence(false, false, optional); err != nil {
+				return err
+			}
+			for _, c := range components {
+				if err := c.Encode(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Decode: func(d *Decoder) (any, error) {
+			if _, _, err := d.DecodeSequence(false, len(optional)); err != nil {
+				return nil, err
+			}
+			values := make([]any, len(components))
+			for i, c := range components {
+				value, err := c.Decode(d)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = value
+			}
+			return values, nil
+		},
+		Value: values,
+	}
+}
+
+// Name is the Name type of the X.691 Annex A.1 example.
+func Name(given, initial, family string) Case {
+	return Record(nil, Visible(given), Visible(initial), Visible(family))
+}
+
+// PersonnelRecord is the value of the X.691 Annex A.1 example, the SET components in canonical
+// tag order: name, number, title, dateOfHire, nameOfSpouse and the children present.
+var PersonnelRecord = Record([]bool{true},
+	Name("John", "P", "Smith"),
+	Integer(51, Unconstrained()),
+	Visible("Director"),
+	Visible("19710917"),
+	Name("Mary", "T", "Smith"),
+	Count(2),
+	Record(nil, Name("Ralph", "T", "Smith"), Visible("19571111")),
+	Record(nil, Name("Susan", "B", "Jones"), Visible("19590717")),
+)
+
+func TestVectors(t *testing.T) {
+	vectors := []Vector{
+		// 11.5: constrained whole number in a minimal bit-field
+		{"integer 0..7", Integer(5, Constrained(0, 7)), "A0", "A0"},
+		// 11.5.7.2: range of 256 is a one octet aligned field
+		{"integer 0..255", Leading(Integer(3, Constrained(0, 255))), "8003", "8180"},
+		// 11.5.7.3: range up to 64K is a two octet aligned field
+		{"integer 0..65535", Integer(0x1234, Constrained(0, 65535)), "1234", "1234"},
+		// 11.5.7.4: larger ranges carry a length in octets
+		{"integer 0..2^32-1", Integer(256, Constrained(0, math.MaxUint32)), "400100", "00000100"},
+		{"integer -5..5", Integer(-2, Constrained(-5, 5)), "30", "30"},
+		{"integer single value", Integer(9, Constrained(9, 9)), "00", "00"},
+		// 11.7: semi-constrained whole number
+		{"integer 0..", Integer(128, SemiConstrained(0)), "0180", "0180"},
+		{"integer 1000..", Integer(1200, SemiConstrained(1000)), "01C8", "01C8"},
+		// 11.8: unconstrained whole number in two's complement
+		{"integer -1", Integer(-1, Unconstrained()), "01FF", "01FF"},
+		{"integer 128", Integer(128, Unconstrained()), "020080", "020080"},
+		{"integer -129", Integer(-129, Unconstrained()), "02FF7F", "02FF7F"},
+		{"integer min", Integer(math.MinInt64, Unconstrained()), "088000000000000000", "088000000000000000"},
+		// 13.2: extensible constraint
+		{"integer 0..7,... in root", Integer(5, Constrained(0, 7).Extended()), "50", "50"},
+		{"integer 0..7,... extension", Integer(9, Constrained(0, 7).Extended()), "800109", "808480"},
+		// 16.9: fixed size bit string up to 16 bits is not aligned
+		{"bit string size 4", Leading(Bits([]byte{0xB0}, 4, Size(4, 4))), "D8", "D8"},
+		// 16.10: fixed size bit string above 16 bits is aligned
+		{"bit string size 20", Leading(Bits([]byte{0xAB, 0xCD, 0xE0}, 20, Size(20, 20))), "80ABCDE0", "D5E6F0"},
+		// 16.11: variable size bit string carries a length
+		{"bit string size 0..8", Bits([]byte{0xA0}, 3, Size(0, 8)), "30A0", "3A"},
+		{"bit string unsized", Bits([]byte{0xFF, 0xC0}, 10, Unsized()), "0AFFC0", "0AFFC0"},
+		{"bit string extension", Bits([]byte{0xC0}, 2, Size(4, 4).Extended()), "8002C0", "8160"},
+		// 17.6: fixed size octet string up to two octets is not aligned
+		{"octet string size 2", Leading(Octets([]byte{0xAB, 0xCD}, Size(2, 2))), "D5E680", "D5E680"},
+		// 17.7: fixed size octet string above two octets is aligned
+		{"octet string size 3", Leading(Octets([]byte{0xAB, 0xCD, 0xEF}, Size(3, 3))), "80ABCDEF", "D5E6F780"},
+		// 17.8: variable size octet string carries a length
+		{"octet string unsized", Octets([]byte{1, 2, 3}, Unsized()), "03010203", "03010203"},
+		{"octet string size 1..4", Leading(Octets([]byte{0xAA, 0xBB}, Size(1, 4))), "A0AABB", "B55760"},
+		{"octet string empty", Octets([]byte{}, Size(0, 0)), "00", "00"},
+		// Annex A.1: record that does not use subtype constraints
+		{"annex A.1 personnel record", PersonnelRecord,
+			"80044A6F686E015005536D6974680133084469726563746F72083139373130393137" +
+				"044D617279015405536D697468020552616C7068015405536D697468083139353731313131" +
+				"05537573616E0142054A6F6E6573083139353930373137",
+			"824ADFA3700D005A7B74F4D0026611134F2CB8FA6FE410C5CB762C1CB16E09370F2F2035" +
+				"0169EDD3D340102D2C3B386801A80B4F6E9E9A0218B96ADD8B162C4169F5E787700C2059" +
+				"5BF765E610C5CB572C1BB16E"},
+	}
+	for _, v := range vectors {
+		for _, aligned := range []bool{true, false} {
+			want := v.Unaligned
+			if aligned {
+				want = v.Aligned
+			}
+			t.Run(fmt.Sprintf("%s/aligned=%t", v.Name, aligned), func(t *testing.T) {
+				encoder := CreateEncoder(aligned)
+				if err := v.Case.Encode(encoder); err != nil {
+					t.Fatalf("encode error: %v", err)
+				}
+				if got := fmt.Sprintf("%X", encoder.Bytes()); got != want {
+					t.Errorf("encoded: got %s, want %s", got, want)
+				}
+				data, _ := hex.DecodeString(want)
+				value, err := v.Case.Decode(CreateDecoder(data, aligned))
+				if err != nil {
+					t.Fatalf("decode error: %v", err)
+				}
+				if fmt.Sprint(value) != fmt.Sprint(v.Case.Value) {
+					t.Errorf("mismatch: wrote %v, read %v", v.Case.Value, value)
+				}
+			})
+		}
+	}
+}
+
+func TestConstructed(t *testing.T) {
+	for _, aligned := range []bool{true, false} {
+		// Message ::= SEQUENCE { id INTEGER (0..15), name OCTET STRING OPTIONAL,
+		//   flag BOOLEAN OPTIONAL, kind ENUMERATED { a, b, c, ... },
+		//   body CHOICE { small INTEGER (0..3), large INTEGER, ... }, ..., extra INTEGER (0..255) }
+		encoder := CreateEncoder(aligned)
+		steps := []error{
+			encoder.EncodeSequence(true, true, []bool{true, false}),
+			encoder.EncodeInteger(12, Constrained(0, 15)),
+			encoder.EncodeOctetString([]byte("abc"), Unsized()),
+			encoder.EncodeEnumerated(4, 3, true),
+			encoder.EncodeChoice(1, 2, true),
+			encoder.EncodeInteger(-300, Unconstrained()),
+			encoder.EncodeExtensions([]bool{true}),
+		}
+		extra := CreateEncoder(aligned)
+		steps = append(steps, extra.EncodeInteger(200, Constrained(0, 255)))
+		steps = append(steps, encoder.EncodeOpenType(extra.Bytes()))
+		for i, err := range steps {
+			if err != nil {
+				t.Fatalf("aligned=%t: step %d: encode error: %v", aligned, i, err)
+			}
+		}
+
+		decoder := CreateDecoder(encoder.Bytes(), aligned)
+		extended, optional, err := decoder.DecodeSequence(true, 2)
+		if err != nil || !extended || !optional[0] || optional[1] {
+			t.Fatalf("aligned=%t: sequence: got %t %v, err: %v", aligned, extended, optional, err)
+		}
+		if id, err := decoder.DecodeInteger(Constrained(0, 15)); err != nil || id != 12 {
+			t.Errorf("aligned=%t: id: got %d, err: %v", aligned, id, err)
+		}
+		if name, err := decoder.DecodeOctetString(Unsized()); err != nil || string(name) != "abc" {
+			t.Errorf("aligned=%t: name: got %q, err: %v", aligned, name, err)
+		}
+		if kind, err := decoder.DecodeEnumerated(3, true); err != nil || kind != 4 {
+			t.Errorf("aligned=%t: kind: got %d, err: %v", aligned, kind, err)
+		}
+		if choice, err := decoder.DecodeChoice(2, true); err != nil || choice != 1 {
+			t.Errorf("aligned=%t: choice: got %d, err: %v", aligned, choice, err)
+		}
+		if large, err := decoder.DecodeInteger(Unconstrained()); err != nil || large != -300 {
+			t.Errorf("aligned=%t: large: got %d, err: %v", aligned, large, err)
+		}
+		present, err := decoder.DecodeExtensions()
+		if err != nil || len(present) != 1 || !present[0] {
+			t.Fatalf("aligned=%t: extensions: got %v, err: %v", aligned, present, err)
+		}
+		open, err := decoder.DecodeOpenType()
+		if err != nil {
+			t.Fatalf("aligned=%t: open type error: %v", aligned, err)
+		}
+		if value, err := CreateDecoder(open, aligned).DecodeInteger(Constrained(0, 255)); err != nil || value != 200 {
+			t.Errorf("aligned=%t: extra: got %d, err: %v", aligned, value, err)
+		}
+	}
+}
+
+func TestEnumeratedVectors(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		root  int
+		ext   bool
+		want  string
+	}{
+		// 14.2: root index as a constrained whole number after the extension bit
+		{"root", 1, 3, true, "20"},
+		// 14.3: extension index as a normally small non-negative whole number
+		{"first extension", 3, 3, true, "80"},
+		{"large extension", 3 + 100, 3, true, "C00164"},
+		{"not extensible", 2, 3, false, "80"},
+	}
+	for _, tt := range tests {
+		encoder := CreateEncoder(true)
+		if err := encoder.EncodeEnumerated(tt.index, tt.root, tt.ext); err != nil {
+			t.Fatalf("%s: encode error: %v", tt.name, err)
+		}
+		if got := fmt.Sprintf("%X", encoder.Bytes()); got != tt.want {
+			t.Errorf("%s: encoded: got %s, want %s", tt.name, got, tt.want)
+		}
+		index, err := CreateDecoder(encoder.Bytes(), true).DecodeEnumerated(tt.root, tt.ext)
+		if err != nil || index != tt.index {
+			t.Errorf("%s: decoded: got %d, err: %v", tt.name, index, err)
+		}
+	}
+}
+
+func TestFragmentation(t *testing.T) {
+	for _, size := range []int{fragment - 1, fragment, fragment + 5, 5*fragment + 1} {
+		data := bytes.Repeat([]byte{0x5A}, size)
+		for _, aligned := range []bool{true, false} {
+			encoder := CreateEncoder(aligned)
+			if err := encoder.EncodeOctetString(data, Unsized()); err != nil {
+				t.Fatalf("size %d: encode error: %v", size, err)
+			}
+			encoded := encoder.Bytes()
+			if size >= fragment && encoded[0] != 0xC0|byte(min(4, size/fragment)) {
+				t.Errorf("size %d: fragment header: got %02X", size, encoded[0])
+			}
+			decoded, err := CreateDecoder(encoded, aligned).DecodeOctetString(Unsized())
+			if err != nil {
+				t.Fatalf("size %d: decode error: %v", size, err)
+			}
+			if !bytes.Equal(decoded, data) {
+				t.Errorf("size %d: mismatch after round trip", size)
+			}
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	encoder := CreateEncoder(true)
+	if err := encoder.EncodeInteger(8, Constrained(0, 7)); err == nil {
+		t.Errorf("expected error for value outside constraint")
+	}
+	if err := encoder.EncodeOctetString([]byte{1, 2, 3}, Size(1, 2)); err == nil {
+		t.Errorf("expected error for size outside constraint")
+	}
+	if err := encoder.EncodeEnumerated(3, 3, false); err == nil {
+		t.Errorf("expected error for index outside root")
+	}
+	if err := encoder.EncodeSequence(false, true, nil); err == nil {
+		t.Errorf("expected error for extension in non-extensible sequence")
+	}
+	if _, err := CreateDecoder([]byte{0x05, 0x01}, true).DecodeOctetString(Unsized()); err == nil {
+		t.Errorf("expected error for truncated octet string")
+	}
+}
@@ -0,0 +1,54 @@
+package bitbuffer
+
+import "sync"
+
+// Pool reuses writer and reader Codecs across goroutines to avoid allocating a buffer per message.
+// It is safe for concurrent use; each acquired Codec belongs to the caller until released.
+type Pool struct {
+	writers sync.Pool
+	readers sync.Pool
+	opts    []Option
+	limit   int
+}
+
+// CreatePool creates a new Pool whose Codecs are configured with opts. Writers that have grown
+// beyond limit bytes are dropped on release rather than kept, zero meaning no limit.
+func CreatePool(limit int, opts ...Option) *Pool {
+	p := &Pool{opts: opts, limit: limit}
+	p.writers.New = func() any {
+		return CreateWriter(p.opts...)
+	}
+	p.readers.New = func() any {
+		return CreateReader(nil, p.opts...)
+	}
+	return p
+}
+
+// Acquire returns an empty writer.
+func (p *Pool) Acquire() *Codec {
+	return p.writers.Get().(*Codec)
+}
+
+// Release resets the writer and returns it to the pool. Neither the writer nor its Buff may be
+// used after release.
+func (p *Pool) Release(w *Codec) {
+	if p.limit > 0 && w.Cap() > p.limit {
+		return
+	}
+	w.Reset()
+	p.writers.Put(w)
+}
+
+// AcquireReader returns a reader over data.
+func (p *Pool) AcquireReader(data []byte) *Codec {
+	w := p.readers.Get().(*Codec)
+	w.Buff = data
+	return w
+}
+
+// ReleaseReader resets the reader and returns it to the pool, dropping its reference to the data.
+func (p *Pool) ReleaseReader(w *Codec) {
+	w.Reset()
+	w.Buff = nil
+	p.readers.Put(w)
+}
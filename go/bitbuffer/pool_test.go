@@ -0,0 +1,85 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestCapacity(t *testing.T) {
+	if w := CreateWriter(); w.Cap() != InitialBufferSize {
+		t.Errorf("default capacity: got %d, want %d", w.Cap(), InitialBufferSize)
+	}
+	if w := CreateWriter(WithCapacity(1500)); w.Cap() != 1500 {
+		t.Errorf("capacity: got %d, want %d", w.Cap(), 1500)
+	}
+
+	// A streaming writer flushes once more than its capacity is buffered
+	var out bytes.Buffer
+	w := CreateStreamWriter(&out, WithCapacity(4))
+	w.WriteBytes([]byte{1, 2, 3})
+	if out.Len() != 0 {
+		t.Errorf("flushed early: %d bytes", out.Len())
+	}
+	w.Write(8, 4)
+	if out.Len() != 4 {
+		t.Errorf("flushed: got %d bytes, want %d", out.Len(), 4)
+	}
+}
+
+func TestReset(t *testing.T) {
+	w := CreateWriter(WithTrace())
+	w.Write(12, 0xABC)
+	w.Read(4)
+	data := &w.Buff[:1][0]
+	w.Reset()
+	if w.Len() != 0 || w.NumWritten() != 0 || w.NumRead() != 0 || w.Position() != 0 || len(w.Trace().Events) != 0 {
+		t.Errorf("state not cleared: len %d, written %d, read %d, position %d", w.Len(), w.NumWritten(), w.NumRead(), w.Position())
+	}
+	w.Write(4, 0x5)
+	if &w.Buff[0] != data {
+		t.Errorf("buffer reallocated")
+	}
+	if !bytes.Equal(w.Buff, []byte{0x50}) {
+		t.Errorf("stale bits after reset: % X", w.Buff)
+	}
+}
+
+func TestPool(t *testing.T) {
+	pool := CreatePool(256, WithCapacity(16))
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				w := pool.Acquire()
+				if w.NumWritten() != 0 || w.Len() != 0 {
+					t.Errorf("acquired dirty writer: %d bits", w.NumWritten())
+					return
+				}
+				count := i % 100
+				for j := range count {
+					w.Write(7, uint64(g+j))
+				}
+				r := pool.AcquireReader(w.Buff)
+				for j := range count {
+					if value, err := r.Read(7); err != nil || value != uint64(g+j)&0x7F {
+						t.Errorf("mismatch: wrote %d, read %d, err: %v", g+j, value, err)
+					}
+				}
+				pool.ReleaseReader(r)
+				pool.Release(w)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Oversized writers are not kept
+	w := pool.Acquire()
+	w.WriteBytes(make([]byte, 1024))
+	pool.Release(w)
+	if got := pool.Acquire(); got == w {
+		t.Errorf("oversized writer reused")
+	}
+}
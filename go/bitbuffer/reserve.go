@@ -0,0 +1,75 @@
+package bitbuffer
+
+// Reservation is a field written as zeros whose value is filled in later, such as a length or
+// count that is only known once the data following it has been written.
+type Reservation struct {
+	codec    *Codec
+	position uint64 // Bit position of the field in the stream
+	width    uint8
+	event    int // Index of the traced write, -1 when not tracing
+	set      bool
+}
+
+// Reserve writes num zero bits to be set later through the returned Reservation. The reservation
+// stays valid as the buffer grows, and a streaming writer holds back its flushes until it is set.
+func (w *Codec) Reserve(num uint8) (*Reservation, error) {
+	r := &Reservation{codec: w, position: w.bitsWritten, width: num, event: -1}
+	if w.trace != nil {
+		r.event = len(w.trace.Events)
+	}
+	// Count the reservation first so that writing the field cannot flush it
+	w.reserved++
+	if err := w.Write(num, 0); err != nil {
+		w.reserved--
+		return nil, err
+	}
+	return r, nil
+}
+
+// Width returns the width of the reserved field in bits.
+func (r *Reservation) Width() uint8 {
+	return r.width
+}
+
+// Written returns the number of bits written after the reserved field.
+func (r *Reservation) Written() uint64 {
+	return r.codec.bitsWritten - r.position - uint64(r.width)
+}
+
+// Set stores value in the reserved field. It may be called again to change the value until a
+// streaming writer has flushed the field, and must not be called after the Codec is Reset.
+func (r *Reservation) Set(value uint64) error {
+	w := r.codec
+	if r.width < 64 && value>>r.width != 0 {
+		return &RangeError{Value: int64(value), Bits: r.width, Unsigned: true}
+	}
+	flushed := w.flushed()
+	if r.position < flushed {
+		return &BitError{Op: "set", Position: r.position, Width: uint64(r.width), Err: ErrInvalidPosition}
+	}
+	start := r.position - flushed
+	for i := range uint64(r.width) {
+		var (
+			p    = start + i
+			bit  = value >> (uint64(r.width) - 1 - i) & 1
+			mask = byte(0x80) >> (p % 8)
+		)
+		if w.order == LSBFirst {
+			bit = value >> i & 1
+			mask = byte(1) << (p % 8)
+		}
+		if bit == 1 {
+			w.Buff[p/8] |= mask
+		} else {
+			w.Buff[p/8] &^= mask
+		}
+	}
+	if w.trace != nil && r.event >= 0 && r.event < len(w.trace.Events) {
+		w.trace.Events[r.event].Value = value
+	}
+	if !r.set {
+		r.set = true
+		w.reserved = max(w.reserved-1, 0)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		w := CreateWriter(WithBitOrder(order), WithCapacity(1))
+		w.Write(3, 5)
+		length, err := w.Reserve(13)
+		if err != nil {
+			t.Fatalf("reserve error: %v", err)
+		}
+		flag, _ := w.Reserve(1)
+		// Force several reallocations between reserving and setting
+		for i := range 1000 {
+			w.Write(8, uint64(i))
+		}
+		if err := length.Set(length.Written() / 8); err != nil {
+			t.Fatalf("set error: %v", err)
+		}
+		flag.Set(0)
+		flag.Set(1)
+
+		r := CreateReader(w.Buff, WithBitOrder(order))
+		values := []uint64{5, 1000, 1}
+		for i, num := range []uint8{3, 13, 1} {
+			if value, _ := r.Read(num); value != values[i] {
+				t.Errorf("order %d: mismatch: wrote %d, read %d", order, values[i], value)
+			}
+		}
+		for i := range 1000 {
+			if value, _ := r.Read(8); value != uint64(i)&0xFF {
+				t.Fatalf("order %d: body mismatch at %d: read %d", order, i, value)
+			}
+		}
+	}
+}
+
+func TestReserveStream(t *testing.T) {
+	var (
+		out  bytes.Buffer
+		want = CreateWriter()
+		w    = CreateStreamWriter(&out, WithCapacity(4))
+	)
+	length, _ := w.Reserve(16)
+	for i := range 100 {
+		w.Write(8, uint64(i))
+	}
+	if out.Len() != 0 {
+		t.Errorf("flushed %d bytes before the reservation was set", out.Len())
+	}
+	length.Set(100)
+	w.Write(4, 0xF)
+	w.Close()
+
+	want.Write(16, 100)
+	for i := range 100 {
+		want.Write(8, uint64(i))
+	}
+	want.Write(4, 0xF)
+	if !bytes.Equal(out.Bytes(), want.Buff) {
+		t.Errorf("stream mismatch:\n% X\nwant:\n% X", out.Bytes(), want.Buff)
+	}
+	if err := length.Set(1); !errors.Is(err, ErrInvalidPosition) {
+		t.Errorf("expected ErrInvalidPosition, got %v", err)
+	}
+}
+
+func TestReserveAfterBufferedData(t *testing.T) {
+	var (
+		out bytes.Buffer
+		w   = CreateStreamWriter(&out, WithCapacity(4))
+	)
+	w.Write(24, 0xAABBCC)
+	length, _ := w.Reserve(16)
+	w.Write(8, 0x11)
+	if err := length.Set(0x1234); err != nil {
+		t.Fatalf("set error: %v", err)
+	}
+	for range 8 {
+		w.Write(8, 0x22)
+	}
+	if out.Len() == 0 {
+		t.Errorf("no flush after the reservation was set")
+	}
+	w.Close()
+	want := []byte{0xAA, 0xBB, 0xCC, 0x12, 0x34, 0x11, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22, 0x22}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("stream mismatch:\n% X\nwant:\n% X", out.Bytes(), want)
+	}
+}
+
+func TestReserveErrors(t *testing.T) {
+	w := CreateWriter(WithTrace())
+	if _, err := w.Reserve(0); !errors.Is(err, ErrInvalidWidth) {
+		t.Errorf("expected ErrInvalidWidth, got %v", err)
+	}
+	count, _ := w.Label("count").Reserve(4)
+	if err := count.Set(16); !errors.Is(err, ErrRange) {
+		t.Errorf("expected ErrRange, got %v", err)
+	}
+	if err := count.Set(1 << 63); err == nil || err.Error() != "value 9223372036854775808 does not fit in 4 bits" {
+		t.Errorf("unexpected error for a large value: %v", err)
+	}
+	count.Set(9)
+	if dump := w.Trace().String(); !strings.Contains(dump, "1001 .... = 9") {
+		t.Errorf("trace not updated:\n%s", dump)
+	}
+}
@@ -0,0 +1,85 @@
+package bitbuffer
+
+// WriteSigned writes value as a num bit two's complement integer.
+func (w *Codec) WriteSigned(num uint8, value int64) error {
+	if num < 1 || num > 64 {
+		return w.writeError("write", uint64(num), ErrInvalidWidth)
+	}
+	if shift := 64 - num; value<<shift>>shift != value {
+		return &RangeError{Value: value, Bits: num}
+	}
+	return w.Write(num, uint64(value))
+}
+
+// ReadSigned reads a num bit two's complement integer, sign extending it to 64 bits.
+func (w *Codec) ReadSigned(num uint8) (int64, error) {
+	if num < 1 || num > 64 {
+		return 0, w.readError("read", uint64(num), ErrInvalidWidth)
+	}
+	value, err := w.Read(num)
+	if err != nil {
+		return 0, err
+	}
+	shift := 64 - num
+	return int64(value<<shift) >> shift, nil
+}
+
+// WriteSignMagnitude writes value as a num bit sign-magnitude integer: a sign bit, set for negative
+// values, followed by num-1 bits of magnitude.
+func (w *Codec) WriteSignMagnitude(num uint8, value int64) error {
+	if num < 1 || num > 64 {
+		return w.writeError("write", uint64(num), ErrInvalidWidth)
+	}
+	var (
+		sign      uint64
+		magnitude = uint64(value)
+	)
+	if value < 0 {
+		sign = 1
+		magnitude = -magnitude
+	}
+	if magnitude>>(num-1) != 0 {
+		return &RangeError{Value: value, Bits: num}
+	}
+	return w.Write(num, sign<<(num-1)|magnitude)
+}
+
+// ReadSignMagnitude reads a num bit sign-magnitude integer. Negative zero reads as zero.
+func (w *Codec) ReadSignMagnitude(num uint8) (int64, error) {
+	if num < 1 || num > 64 {
+		return 0, w.readError("read", uint64(num), ErrInvalidWidth)
+	}
+	value, err := w.Read(num)
+	if err != nil {
+		return 0, err
+	}
+	magnitude := int64(value & (1<<(num-1) - 1))
+	if value>>(num-1) == 1 {
+		return -magnitude, nil
+	}
+	return magnitude, nil
+}
+
+// WriteZigZag writes value as a num bit zig-zag integer, mapping 0, -1, 1, -2, ... to 0, 1, 2, 3, ...
+func (w *Codec) WriteZigZag(num uint8, value int64) error {
+	if num < 1 || num > 64 {
+		return w.writeError("write", uint64(num), ErrInvalidWidth)
+	}
+	encoded := uint64(value<<1) ^ uint64(value>>63)
+	if num < 64 && encoded>>num != 0 {
+		return &RangeError{Value: value, Bits: num}
+	}
+	return w.Write(num, encoded)
+}
+
+// ReadZigZag reads a num bit zig-zag integer.
+func (w *Codec) ReadZigZag(num uint8) (int64, error) {
+	if num < 1 || num > 64 {
+		return 0, w.readError("read", uint64(num), ErrInvalidWidth)
+	}
+	value, err := w.Read(num)
+	if err != nil {
+		return 0, err
+	}
+	return int64(value>>1) ^ -int64(value&1), nil
+}
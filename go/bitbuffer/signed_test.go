@@ -0,0 +1,85 @@
+package bitbuffer
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSignedVectors(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*Codec, uint8, int64) error
+		read  func(*Codec, uint8) (int64, error)
+		num   uint8
+		value int64
+		bits  string
+	}{
+		{"twos -1", (*Codec).WriteSigned, (*Codec).ReadSigned, 4, -1, "1111"},
+		{"twos -8", (*Codec).WriteSigned, (*Codec).ReadSigned, 4, -8, "1000"},
+		{"twos 7", (*Codec).WriteSigned, (*Codec).ReadSigned, 4, 7, "0111"},
+		{"twos min", (*Codec).WriteSigned, (*Codec).ReadSigned, 64, math.MinInt64, "1" + strings.Repeat("0", 63)},
+		{"sign magnitude -3", (*Codec).WriteSignMagnitude, (*Codec).ReadSignMagnitude, 4, -3, "1011"},
+		{"sign magnitude 3", (*Codec).WriteSignMagnitude, (*Codec).ReadSignMagnitude, 4, 3, "0011"},
+		{"sign magnitude -7", (*Codec).WriteSignMagnitude, (*Codec).ReadSignMagnitude, 4, -7, "1111"},
+		{"zigzag 0", (*Codec).WriteZigZag, (*Codec).ReadZigZag, 4, 0, "0000"},
+		{"zigzag -1", (*Codec).WriteZigZag, (*Codec).ReadZigZag, 4, -1, "0001"},
+		{"zigzag 1", (*Codec).WriteZigZag, (*Codec).ReadZigZag, 4, 1, "0010"},
+		{"zigzag -8", (*Codec).WriteZigZag, (*Codec).ReadZigZag, 4, -8, "1111"},
+		{"zigzag max", (*Codec).WriteZigZag, (*Codec).ReadZigZag, 64, math.MaxInt64, strings.Repeat("1", 63) + "0"},
+		{"zigzag min", (*Codec).WriteZigZag, (*Codec).ReadZigZag, 64, math.MinInt64, strings.Repeat("1", 64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := CreateWriter()
+			if err := tt.write(writer, tt.num, tt.value); err != nil {
+				t.Fatalf("write error: %v", err)
+			}
+			ExpectBits(t, writer, tt.bits)
+			value, err := tt.read(CreateReader(writer.Buff), tt.num)
+			if err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			if value != tt.value {
+				t.Errorf("mismatch: wrote %d, read %d", tt.value, value)
+			}
+		})
+	}
+}
+
+func TestSignedRange(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*Codec, uint8, int64) error
+		num   uint8
+		value int64
+	}{
+		{"twos above", (*Codec).WriteSigned, 4, 8},
+		{"twos below", (*Codec).WriteSigned, 4, -9},
+		{"sign magnitude above", (*Codec).WriteSignMagnitude, 4, 8},
+		{"sign magnitude below", (*Codec).WriteSignMagnitude, 4, -8},
+		{"sign magnitude min", (*Codec).WriteSignMagnitude, 64, math.MinInt64},
+		{"zigzag above", (*Codec).WriteZigZag, 4, 8},
+		{"zigzag below", (*Codec).WriteZigZag, 4, -9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := CreateWriter()
+			err := tt.write(writer, tt.num, tt.value)
+			var rangeErr *RangeError
+			if !errors.As(err, &rangeErr) {
+				t.Fatalf("expected RangeError, got %v", err)
+			}
+			if rangeErr.Value != tt.value || rangeErr.Bits != tt.num {
+				t.Errorf("unexpected error fields: %+v", rangeErr)
+			}
+			if writer.NumWritten() != 0 {
+				t.Errorf("bits written on range error: %d", writer.NumWritten())
+			}
+		})
+	}
+	if err := CreateWriter().WriteSigned(0, 0); err == nil {
+		t.Errorf("expected error for zero width")
+	}
+}
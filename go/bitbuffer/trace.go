@@ -0,0 +1,189 @@
+package bitbuffer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Event records one traced operation on the bit stream.
+type Event struct {
+	Op     string // Operation, "read", "write" or "skip"
+	Offset uint64 // Bit position of the first bit
+	Width  uint64 // Number of bits
+	Value  uint64 // Bits read or written, zero for skips
+	Label  string // Label set with Codec.Label before the operation
+}
+
+// Trace collects the events of a Codec created with WithTrace.
+type Trace struct {
+	Events []Event
+	order  BitOrder // Bit order of the traced Codec, used to place bits in the dump
+	label  string   // Label for the next event
+}
+
+// WithTrace enables tracing of every read, write and skip on the Codec. Peek is not traced.
+func WithTrace() Option {
+	return func(w *Codec) {
+		w.trace = &Trace{}
+	}
+}
+
+// Trace returns the trace of the Codec, nil when tracing is disabled.
+func (w *Codec) Trace() *Trace {
+	return w.trace
+}
+
+// Label names the next traced operation, returning the Codec so calls can be chained as in
+// w.Label("version").Read(4). It does nothing when tracing is disabled.
+func (w *Codec) Label(label string) *Codec {
+	if w.trace != nil {
+		w.trace.label = label
+	}
+	return w
+}
+
+// record appends an event to the trace, consuming the pending label.
+func (w *Codec) record(op string, offset, width, value uint64) {
+	if w.trace == nil {
+		return
+	}
+	w.trace.order = w.order
+	w.trace.Events = append(w.trace.Events, Event{Op: op, Offset: offset, Width: width, Value: value, Label: w.trace.label})
+	w.trace.label = ""
+}
+
+// Reset discards the recorded events.
+func (t *Trace) Reset() {
+	t.Events = t.Events[:0]
+	t.label = ""
+}
+
+// bits renders the bytes covered by the event in binary, grouped by nibble, with the bits of the
+// event shown as digits and the other bits as dots.
+func (t *Trace) bits(e Event) string {
+	if e.Width == 0 || e.Width > 64 {
+		return fmt.Sprintf("(%d bits)", e.Width)
+	}
+	var (
+		first = e.Offset / 8
+		last  = (e.Offset + e.Width - 1) / 8
+		b     strings.Builder
+	)
+	for index := first; index <= last; index++ {
+		if index > first {
+			b.WriteByte(' ')
+		}
+		for column := uint64(0); column < 8; column++ {
+			if column == 4 {
+				b.WriteByte(' ')
+			}
+			// Columns show the byte most significant bit first whatever the bit order
+			position := 8*index + column
+			if t.order == LSBFirst {
+				position = 8*index + 7 - column
+			}
+			if position < e.Offset || position >= e.Offset+e.Width {
+				b.WriteByte('.')
+				continue
+			}
+			i := position - e.Offset
+			if t.order != LSBFirst {
+				i = e.Width - 1 - i
+			}
+			if e.Op == "skip" {
+				b.WriteByte('x')
+			} else {
+				b.WriteByte(byte('0' + e.Value>>i&1))
+			}
+		}
+	}
+	return b.String()
+}
+
+// format renders a single event as a dump line, padding the bits column to size.
+func (t *Trace) format(e Event, size int) string {
+	value := fmt.Sprintf("%d", e.Value)
+	if e.Op == "skip" {
+		value = "-"
+	}
+	return strings.TrimRight(fmt.Sprintf("%8d %6d %-5s %-*s = %-20s %s", e.Offset, e.Width, e.Op, size, t.bits(e), value, e.Label), " ")
+}
+
+// width returns the longest bits column of the trace.
+func (t *Trace) width() int {
+	size := 0
+	for _, e := range t.Events {
+		size = max(size, len(t.bits(e)))
+	}
+	return size
+}
+
+// lines renders every event as a dump line with a bits column of the given size.
+func (t *Trace) lines(size int) []string {
+	lines := make([]string, len(t.Events))
+	for i, e := range t.Events {
+		lines[i] = t.format(e, size)
+	}
+	return lines
+}
+
+// String renders the annotated bit-level dump, one line per event with its offset, width, bits,
+// value and label. A blank line separates events that do not follow on from each other, such as
+// reads after a seek.
+func (t *Trace) String() string {
+	var b strings.Builder
+	t.Dump(&b)
+	return b.String()
+}
+
+// Dump writes the annotated bit-level dump to out.
+func (t *Trace) Dump(out io.Writer) error {
+	size := t.width()
+	if _, err := fmt.Fprintf(out, "%8s %6s %-5s %-*s   %-20s %s\n", "offset", "width", "op", size, "bits", "value", "label"); err != nil {
+		return err
+	}
+	for i, line := range t.lines(size) {
+		if i > 0 {
+			previous := t.Events[i-1]
+			if previous.Offset+previous.Width != t.Events[i].Offset {
+				if _, err := io.WriteString(out, "\n"); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Diff compares the events of two traces by position, returning the dump lines that differ
+// prefixed with "-" for expected and "+" for actual. Operations are not compared, so the trace of
+// an encoder can be diffed against the trace of a decoder. It returns an empty string for equal
+// layouts.
+func Diff(expected, actual *Trace) string {
+	var (
+		size = max(expected.width(), actual.width())
+		want = expected.lines(size)
+		got  = actual.lines(size)
+		b    strings.Builder
+	)
+	for i := 0; i < max(len(want), len(got)); i++ {
+		switch {
+		case i >= len(got):
+			fmt.Fprintf(&b, "-%s\n", want[i])
+		case i >= len(want):
+			fmt.Fprintf(&b, "+%s\n", got[i])
+		case !same(expected.Events[i], actual.Events[i]):
+			fmt.Fprintf(&b, "-%s\n+%s\n", want[i], got[i])
+		}
+	}
+	return b.String()
+}
+
+// same reports whether two events describe the same field.
+func same(a, b Event) bool {
+	return a.Offset == b.Offset && a.Width == b.Width && a.Value == b.Value && a.Label == b.Label
+}
@@ -0,0 +1,84 @@
+package bitbuffer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTraceDump(t *testing.T) {
+	w := CreateWriter(WithTrace())
+	w.Label("version").Write(4, 4)
+	w.Label("ihl").Write(4, 5)
+	w.Label("flags").Write(3, 2)
+	w.Label("fragment").Write(13, 0x1234)
+	// The bits column is as wide as the widest field
+	want := strings.Join([]string{
+		"  offset  width op    bits                  value                label",
+		"       0      4 write 0100 ....           = 4                    version",
+		"       4      4 write .... 0101           = 5                    ihl",
+		"       8      3 write 010. ....           = 2                    flags",
+		"      11     13 write ...1 0010 0011 0100 = 4660                 fragment",
+		"",
+	}, "\n")
+	if got := w.Trace().String(); got != want {
+		t.Errorf("dump mismatch:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTraceBitOrder(t *testing.T) {
+	r := CreateReader([]byte{0x45, 0x80}, WithBitOrder(LSBFirst), WithTrace())
+	r.Label("a").Read(3)
+	r.Peek(4)
+	r.Label("b").Read(10)
+	r.Skip(3)
+	lines := strings.Split(r.Trace().String(), "\n")
+	want := []string{
+		"       0      3 read  .... .101           = 5                    a",
+		"       3     10 read  0100 0... ...0 0000 = 8                    b",
+		"      13      3 skip  xxx. ....           = -",
+	}
+	if len(lines) != len(want)+2 {
+		t.Fatalf("unexpected dump:\n%s", r.Trace())
+	}
+	for i, line := range want {
+		if lines[i+1] != line {
+			t.Errorf("line %d: got %q, want %q", i, lines[i+1], line)
+		}
+	}
+}
+
+func TestTraceDiff(t *testing.T) {
+	header := IPv4Header{Version: 4, IHL: 5, TTL: 64, Source: [4]byte{10, 0, 0, 1}}
+	w := CreateWriter(WithTrace())
+	if err := w.Encode(&header); err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	r := CreateReader(w.Buff, WithTrace())
+	var decoded IPv4Header
+	if err := r.Decode(&decoded); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if diff := Diff(w.Trace(), r.Trace()); diff != "" {
+		t.Errorf("unexpected difference:\n%s", diff)
+	}
+
+	// A decoder that reads the IHL field one bit too wide shifts every following field
+	r = CreateReader(w.Buff, WithTrace())
+	r.Label("Version").Read(4)
+	r.Label("IHL").Read(5)
+	diff := Diff(w.Trace(), r.Trace())
+	if !strings.Contains(diff, "-       4      4 write .... 0101") || !strings.Contains(diff, "+       4      5 read  .... 0101 0...") {
+		t.Errorf("unexpected difference:\n%s", diff)
+	}
+	if strings.Contains(diff, "Version") {
+		t.Errorf("matching field reported:\n%s", diff)
+	}
+}
+
+func TestTraceDisabled(t *testing.T) {
+	w := CreateWriter()
+	w.Label("ignored").Write(8, 1)
+	if w.Trace() != nil {
+		t.Errorf("unexpected trace: %v", w.Trace())
+	}
+}
@@ -0,0 +1,110 @@
+package bitbuffer
+
+// MaxVarintLen is the maximum length in bytes of a 64-bit varint or LEB128 value.
+const MaxVarintLen = 10
+
+// WriteUvarint writes value as an unsigned LEB128 varint, as used by protocol buffers: seven bits
+// per byte, least significant group first, with the high bit of each byte flagging a continuation.
+// The bytes need not be aligned.
+func (w *Codec) WriteUvarint(value uint64) error {
+	for value >= 0x80 {
+		if err := w.Write(8, value&0x7F|0x80); err != nil {
+			return err
+		}
+		value = value >> 7
+	}
+	return w.Write(8, value)
+}
+
+// ReadUvarint reads an unsigned LEB128 varint. It fails with ErrOverlong on a non-minimal encoding
+// and ErrRange on a value that overflows 64 bits.
+func (w *Codec) ReadUvarint() (uint64, error) {
+	var (
+		position = w.Position()
+		value    uint64
+	)
+	for i := range MaxVarintLen {
+		b, err := w.Read(8)
+		if err != nil {
+			return 0, err
+		}
+		if i == MaxVarintLen-1 && b > 1 {
+			return 0, varintError(position, i, ErrRange)
+		}
+		value = value | (b&0x7F)<<(7*i)
+		if b < 0x80 {
+			if b == 0 && i > 0 {
+				return 0, varintError(position, i, ErrOverlong)
+			}
+			return value, nil
+		}
+	}
+	return 0, varintError(position, MaxVarintLen-1, ErrRange)
+}
+
+// WriteSLEB128 writes value as a signed LEB128 number, as used by DWARF and WebAssembly: the two's
+// complement value in groups of seven bits, ending once the remaining bits are all copies of the sign.
+func (w *Codec) WriteSLEB128(value int64) error {
+	for {
+		b := uint64(value) & 0x7F
+		value = value >> 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return w.Write(8, b)
+		}
+		if err := w.Write(8, b|0x80); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadSLEB128 reads a signed LEB128 number. It fails with ErrOverlong on a non-minimal encoding
+// and ErrRange on a value that overflows 64 bits.
+func (w *Codec) ReadSLEB128() (int64, error) {
+	var (
+		position = w.Position()
+		value    uint64
+		previous uint64
+	)
+	for i := range MaxVarintLen {
+		b, err := w.Read(8)
+		if err != nil {
+			return 0, err
+		}
+		// The last byte holds bit 63, its other bits must repeat it
+		if i == MaxVarintLen-1 && b != 0x00 && b != 0x7F {
+			return 0, varintError(position, i, ErrRange)
+		}
+		value = value | (b&0x7F)<<(7*i)
+		if b < 0x80 {
+			// A final group of sign bits is redundant when the previous group ends with the same sign
+			if i > 0 && ((b == 0x00 && previous&0x40 == 0) || (b == 0x7F && previous&0x40 != 0)) {
+				return 0, varintError(position, i, ErrOverlong)
+			}
+			if shift := 7 * (i + 1); shift < 64 && b&0x40 != 0 {
+				value = value | ^uint64(0)<<shift
+			}
+			return int64(value), nil
+		}
+		previous = b
+	}
+	return 0, varintError(position, MaxVarintLen-1, ErrRange)
+}
+
+// WriteVarint writes value as a protocol buffers zig-zag varint, sint64 in protobuf terms.
+func (w *Codec) WriteVarint(value int64) error {
+	return w.WriteUvarint(uint64(value<<1) ^ uint64(value>>63))
+}
+
+// ReadVarint reads a protocol buffers zig-zag varint.
+func (w *Codec) ReadVarint() (int64, error) {
+	value, err := w.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(value>>1) ^ -int64(value&1), nil
+}
+
+// varintError creates a BitError for a varint starting at position whose byte index failed.
+func varintError(position uint64, index int, err error) error {
+	return &BitError{Op: "read varint", Position: position, Width: 8 * uint64(index+1), Err: err}
+}
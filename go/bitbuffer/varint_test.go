@@ -0,0 +1,121 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestVarintVectors(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w *Codec) error
+		want  []byte
+	}{
+		{"uvarint 0", func(w *Codec) error { return w.WriteUvarint(0) }, []byte{0x00}},
+		{"uvarint 300", func(w *Codec) error { return w.WriteUvarint(300) }, []byte{0xAC, 0x02}},
+		{"uleb128 624485", func(w *Codec) error { return w.WriteUvarint(624485) }, []byte{0xE5, 0x8E, 0x26}},
+		{"uvarint max", func(w *Codec) error { return w.WriteUvarint(math.MaxUint64) }, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}},
+		{"sleb128 2", func(w *Codec) error { return w.WriteSLEB128(2) }, []byte{0x02}},
+		{"sleb128 -2", func(w *Codec) error { return w.WriteSLEB128(-2) }, []byte{0x7E}},
+		{"sleb128 63", func(w *Codec) error { return w.WriteSLEB128(63) }, []byte{0x3F}},
+		{"sleb128 64", func(w *Codec) error { return w.WriteSLEB128(64) }, []byte{0xC0, 0x00}},
+		{"sleb128 -123456", func(w *Codec) error { return w.WriteSLEB128(-123456) }, []byte{0xC0, 0xBB, 0x78}},
+		{"sleb128 min", func(w *Codec) error { return w.WriteSLEB128(math.MinInt64) }, []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x7F}},
+		{"varint -1", func(w *Codec) error { return w.WriteVarint(-1) }, []byte{0x01}},
+		{"varint 1", func(w *Codec) error { return w.WriteVarint(1) }, []byte{0x02}},
+		{"varint -2", func(w *Codec) error { return w.WriteVarint(-2) }, []byte{0x03}},
+	}
+	for _, tt := range tests {
+		for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+			// The same bytes appear whatever the alignment of the field
+			w := CreateWriter(WithBitOrder(order))
+			w.Write(3, 5)
+			if err := tt.write(w); err != nil {
+				t.Fatalf("%s: write error: %v", tt.name, err)
+			}
+			r := CreateReader(w.Buff, WithBitOrder(order))
+			r.Read(3)
+			if got, _ := r.ReadBytes(len(tt.want)); !bytes.Equal(got, tt.want) || w.NumWritten() != uint64(3+8*len(tt.want)) {
+				t.Errorf("%s: got % X, want % X", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestVarintRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		var (
+			w      = CreateWriter(WithBitOrder(order))
+			values = make([]uint64, 1000)
+			widths = make([]uint8, len(values))
+		)
+		for i := range values {
+			values[i] = rng.Uint64() >> rng.Intn(64)
+			widths[i] = uint8(rng.Intn(8))
+			if widths[i] > 0 {
+				w.Write(widths[i], 0)
+			}
+			w.WriteUvarint(values[i])
+			w.WriteSLEB128(int64(values[i]))
+			w.WriteVarint(-int64(values[i]))
+		}
+		r := CreateReader(w.Buff, WithBitOrder(order))
+		for i, value := range values {
+			r.Read(widths[i])
+			position := r.Position()
+			if got, err := r.ReadUvarint(); err != nil || got != value {
+				t.Fatalf("uvarint mismatch: wrote %d, read %d, err: %v", value, got, err)
+			}
+			// Aligned varints match encoding/binary
+			if position%8 == 0 && order == MSBFirst {
+				if want := binary.AppendUvarint(nil, value); !bytes.Equal(w.Buff[position/8:r.Position()/8], want) {
+					t.Errorf("uvarint %d: got % X, want % X", value, w.Buff[position/8:r.Position()/8], want)
+				}
+			}
+			if got, err := r.ReadSLEB128(); err != nil || got != int64(value) {
+				t.Fatalf("sleb128 mismatch: wrote %d, read %d, err: %v", int64(value), got, err)
+			}
+			if got, err := r.ReadVarint(); err != nil || got != -int64(value) {
+				t.Fatalf("varint mismatch: wrote %d, read %d, err: %v", -int64(value), got, err)
+			}
+		}
+	}
+}
+
+func TestVarintErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		read func(r *Codec) error
+		err  error
+	}{
+		{"uvarint overlong", []byte{0x80, 0x00}, func(r *Codec) error { _, err := r.ReadUvarint(); return err }, ErrOverlong},
+		{"uvarint padded", []byte{0x81, 0x80, 0x00}, func(r *Codec) error { _, err := r.ReadUvarint(); return err }, ErrOverlong},
+		{"uvarint overflow", []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x02}, func(r *Codec) error { _, err := r.ReadUvarint(); return err }, ErrRange},
+		{"uvarint too long", bytes.Repeat([]byte{0x80}, 11), func(r *Codec) error { _, err := r.ReadUvarint(); return err }, ErrRange},
+		{"uvarint truncated", []byte{0x80}, func(r *Codec) error { _, err := r.ReadUvarint(); return err }, ErrNotEnoughBits},
+		{"sleb128 overlong", []byte{0x80, 0x00}, func(r *Codec) error { _, err := r.ReadSLEB128(); return err }, ErrOverlong},
+		{"sleb128 overlong negative", []byte{0xFF, 0x7F}, func(r *Codec) error { _, err := r.ReadSLEB128(); return err }, ErrOverlong},
+		{"sleb128 overflow", []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01}, func(r *Codec) error { _, err := r.ReadSLEB128(); return err }, ErrRange},
+		{"varint overlong", []byte{0x82, 0x00}, func(r *Codec) error { _, err := r.ReadVarint(); return err }, ErrOverlong},
+	}
+	for _, tt := range tests {
+		err := tt.read(CreateReader(tt.data))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	// Minimal encodings that end in a zero or sign byte are accepted
+	for _, data := range [][]byte{{0xC0, 0x00}, {0xBF, 0x7F}} {
+		if _, err := CreateReader(data).ReadSLEB128(); err != nil {
+			t.Errorf("% X: unexpected error: %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package bitbuffer
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// WriteUnary writes value as a run of value one bits terminated by a zero bit.
+func (w *Codec) WriteUnary(value uint64) error {
+	for value > 0 {
+		length := uint8(min(value, 64))
+		if err := w.Write(length, math.MaxUint64); err != nil {
+			return err
+		}
+		value = value - uint64(length)
+	}
+	return w.Write(1, 0)
+}
+
+// ReadUnary reads a run of one bits terminated by a zero bit, returning the run length.
+func (w *Codec) ReadUnary() (uint64, error) {
+	var count uint64
+	for {
+		bit, err := w.Read(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 0 {
+			return count, nil
+		}
+		count++
+	}
+}
+
+// readZeros counts zero bits up to and including the terminating one bit, returning the number of zeros.
+func (w *Codec) readZeros() (uint8, error) {
+	var count uint8
+	for {
+		bit, err := w.Read(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			return count, nil
+		}
+		if count == 63 {
+			return 0, w.readError("read", uint64(count)+1, ErrInvalidCode)
+		}
+		count++
+	}
+}
+
+// writeCode writes the num low bits of value most significant bit first whatever the bit order, so
+// that the bits of a code appear on the wire in the order they are decoded.
+func (w *Codec) writeCode(num uint8, value uint64) error {
+	if w.order == LSBFirst && num > 0 {
+		value = bits.Reverse64(value) >> (64 - num)
+	}
+	return w.Write(num, value)
+}
+
+// readCode reads num bits written by writeCode.
+func (w *Codec) readCode(num uint8) (uint64, error) {
+	value, err := w.Read(num)
+	if err != nil {
+		return 0, err
+	}
+	if w.order == LSBFirst && num > 0 {
+		value = bits.Reverse64(value) >> (64 - num)
+	}
+	return value, nil
+}
+
+// WriteEliasGamma writes a non-zero value as an Elias gamma code.
+func (w *Codec) WriteEliasGamma(value uint64) error {
+	if value == 0 {
+		return fmt.Errorf("%w: elias gamma value must be non-zero", ErrRange)
+	}
+	length := uint8(bits.Len64(value))
+	if length > 1 {
+		if err := w.Write(length-1, 0); err != nil {
+			return err
+		}
+	}
+	return w.writeCode(length, value)
+}
+
+// ReadEliasGamma reads an Elias gamma code.
+func (w *Codec) ReadEliasGamma() (uint64, error) {
+	zeros, err := w.readZeros()
+	if err != nil {
+		return 0, err
+	}
+	rest, err := w.readCode(zeros)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(1)<<zeros | rest, nil
+}
+
+// WriteEliasDelta writes a non-zero value as an Elias delta code.
+func (w *Codec) WriteEliasDelta(value uint64) error {
+	if value == 0 {
+		return fmt.Errorf("%w: elias delta value must be non-zero", ErrRange)
+	}
+	length := uint8(bits.Len64(value))
+	if err := w.WriteEliasGamma(uint64(length)); err != nil {
+		return err
+	}
+	if length > 1 {
+		return w.writeCode(length-1, value)
+	}
+	return nil
+}
+
+// ReadEliasDelta reads an Elias delta code.
+func (w *Codec) ReadEliasDelta() (uint64, error) {
+	length, err := w.ReadEliasGamma()
+	if err != nil {
+		return 0, err
+	}
+	if length > 64 {
+		return 0, w.readError("read", length, ErrInvalidCode)
+	}
+	rest, err := w.readCode(uint8(length - 1))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(1)<<(length-1) | rest, nil
+}
+
+// WriteExpGolomb writes value as an unsigned order-0 Exp-Golomb code, ue(v) in H.264 terms.
+func (w *Codec) WriteExpGolomb(value uint64) error {
+	if value == math.MaxUint64 {
+		return fmt.Errorf("%w: exp-golomb value %d", ErrRange, value)
+	}
+	return w.WriteEliasGamma(value + 1)
+}
+
+// ReadExpGolomb reads an unsigned order-0 Exp-Golomb code, ue(v) in H.264 terms.
+func (w *Codec) ReadExpGolomb() (uint64, error) {
+	value, err := w.ReadEliasGamma()
+	if err != nil {
+		return 0, err
+	}
+	return value - 1, nil
+}
+
+// WriteSignedExpGolomb writes value as a signed order-0 Exp-Golomb code, se(v) in H.264 terms.
+func (w *Codec) WriteSignedExpGolomb(value int64) error {
+	if value == math.MinInt64 {
+		return fmt.Errorf("%w: exp-golomb value %d", ErrRange, value)
+	}
+	if value > 0 {
+		return w.WriteExpGolomb(2*uint64(value) - 1)
+	}
+	return w.WriteExpGolomb(2 * uint64(-value))
+}
+
+// ReadSignedExpGolomb reads a signed order-0 Exp-Golomb code, se(v) in H.264 terms.
+func (w *Codec) ReadSignedExpGolomb() (int64, error) {
+	value, err := w.ReadExpGolomb()
+	if err != nil {
+		return 0, err
+	}
+	if value&1 == 1 {
+		return int64(value/2) + 1, nil
+	}
+	return -int64(value / 2), nil
+}
+
+// WriteRice writes value as a Rice code with parameter k: the quotient in unary followed by k remainder bits.
+func (w *Codec) WriteRice(value uint64, k uint8) error {
+	if k > 63 {
+		return fmt.Errorf("%w: rice parameter must be between 0 and 63", ErrInvalidArgument)
+	}
+	if err := w.WriteUnary(value >> k); err != nil {
+		return err
+	}
+	if k > 0 {
+		return w.writeCode(k, value)
+	}
+	return nil
+}
+
+// ReadRice reads a Rice code with parameter k.
+func (w *Codec) ReadRice(k uint8) (uint64, error) {
+	if k > 63 {
+		return 0, fmt.Errorf("%w: rice parameter must be between 0 and 63", ErrInvalidArgument)
+	}
+	quotient, err := w.ReadUnary()
+	if err != nil {
+		return 0, err
+	}
+	remainder, err := w.readCode(k)
+	if err != nil {
+		return 0, err
+	}
+	return quotient<<k | remainder, nil
+}
+
+// WriteGolomb writes value as a Golomb code with divisor m: the quotient in unary followed by the
+// remainder in truncated binary.
+func (w *Codec) WriteGolomb(value uint64, m uint64) error {
+	if m == 0 {
+		return fmt.Errorf("%w: golomb divisor must be non-zero", ErrInvalidArgument)
+	}
+	if err := w.WriteUnary(value / m); err != nil {
+		return err
+	}
+	var (
+		remainder = value % m
+		length    = uint8(bits.Len64(m - 1))
+		cutoff    = (uint64(1) << length) - m
+	)
+	if length == 0 {
+		return nil
+	}
+	if remainder < cutoff {
+		return w.writeCode(length-1, remainder)
+	}
+	return w.writeCode(length, remainder+cutoff)
+}
+
+// ReadGolomb reads a Golomb code with divisor m.
+func (w *Codec) ReadGolomb(m uint64) (uint64, error) {
+	if m == 0 {
+		return 0, fmt.Errorf("%w: golomb divisor must be non-zero", ErrInvalidArgument)
+	}
+	quotient, err := w.ReadUnary()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		length = uint8(bits.Len64(m - 1))
+		cutoff = (uint64(1) << length) - m
+	)
+	if length == 0 {
+		return quotient * m, nil
+	}
+	remainder, err := w.readCode(length - 1)
+	if err != nil {
+		return 0, err
+	}
+	if remainder >= cutoff {
+		bit, err := w.Read(1)
+		if err != nil {
+			return 0, err
+		}
+		remainder = (remainder<<1 | bit) - cutoff
+	}
+	return quotient*m + remainder, nil
+}
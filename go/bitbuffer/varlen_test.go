@@ -0,0 +1,198 @@
+package bitbuffer
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func FromBitString(bits string, opts ...Option) *Codec {
+	writer := CreateWriter(opts...)
+	for _, bit := range bits {
+		if bit == ' ' {
+			continue
+		}
+		writer.Write(1, uint64(bit-'0'))
+	}
+	return writer
+}
+
+func ExpectBits(t *testing.T, writer *Codec, bits string) {
+	t.Helper()
+	want := FromBitString(bits, WithBitOrder(writer.order))
+	if writer.NumWritten() != want.NumWritten() {
+		t.Errorf("unexpected bits written: got %d, want %d", writer.NumWritten(), want.NumWritten())
+	}
+	if !bytes.Equal(writer.Buff, want.Buff) {
+		t.Errorf("encoded: got % X, want % X (%s)", writer.Buff, want.Buff, bits)
+	}
+}
+
+func TestVariableLengthVectors(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*Codec) error
+		read  func(*Codec) (int64, error)
+		value int64
+		bits  string
+	}{
+		{"unary 0", func(c *Codec) error { return c.WriteUnary(0) }, ReadAs((*Codec).ReadUnary), 0, "0"},
+		{"unary 3", func(c *Codec) error { return c.WriteUnary(3) }, ReadAs((*Codec).ReadUnary), 3, "1110"},
+		{"ue 0", func(c *Codec) error { return c.WriteExpGolomb(0) }, ReadAs((*Codec).ReadExpGolomb), 0, "1"},
+		{"ue 1", func(c *Codec) error { return c.WriteExpGolomb(1) }, ReadAs((*Codec).ReadExpGolomb), 1, "010"},
+		{"ue 2", func(c *Codec) error { return c.WriteExpGolomb(2) }, ReadAs((*Codec).ReadExpGolomb), 2, "011"},
+		{"ue 3", func(c *Codec) error { return c.WriteExpGolomb(3) }, ReadAs((*Codec).ReadExpGolomb), 3, "00100"},
+		{"ue 8", func(c *Codec) error { return c.WriteExpGolomb(8) }, ReadAs((*Codec).ReadExpGolomb), 8, "0001001"},
+		{"se 0", func(c *Codec) error { return c.WriteSignedExpGolomb(0) }, (*Codec).ReadSignedExpGolomb, 0, "1"},
+		{"se 1", func(c *Codec) error { return c.WriteSignedExpGolomb(1) }, (*Codec).ReadSignedExpGolomb, 1, "010"},
+		{"se -1", func(c *Codec) error { return c.WriteSignedExpGolomb(-1) }, (*Codec).ReadSignedExpGolomb, -1, "011"},
+		{"se 2", func(c *Codec) error { return c.WriteSignedExpGolomb(2) }, (*Codec).ReadSignedExpGolomb, 2, "00100"},
+		{"se -2", func(c *Codec) error { return c.WriteSignedExpGolomb(-2) }, (*Codec).ReadSignedExpGolomb, -2, "00101"},
+		{"gamma 1", func(c *Codec) error { return c.WriteEliasGamma(1) }, ReadAs((*Codec).ReadEliasGamma), 1, "1"},
+		{"gamma 2", func(c *Codec) error { return c.WriteEliasGamma(2) }, ReadAs((*Codec).ReadEliasGamma), 2, "010"},
+		{"gamma 5", func(c *Codec) error { return c.WriteEliasGamma(5) }, ReadAs((*Codec).ReadEliasGamma), 5, "00101"},
+		{"gamma 100", func(c *Codec) error { return c.WriteEliasGamma(100) }, ReadAs((*Codec).ReadEliasGamma), 100, "000000 1100100"},
+		{"gamma 4", func(c *Codec) error { return c.WriteEliasGamma(4) }, ReadAs((*Codec).ReadEliasGamma), 4, "00100"},
+		{"delta 1", func(c *Codec) error { return c.WriteEliasDelta(1) }, ReadAs((*Codec).ReadEliasDelta), 1, "1"},
+		{"delta 2", func(c *Codec) error { return c.WriteEliasDelta(2) }, ReadAs((*Codec).ReadEliasDelta), 2, "010 0"},
+		{"delta 10", func(c *Codec) error { return c.WriteEliasDelta(10) }, ReadAs((*Codec).ReadEliasDelta), 10, "00100 010"},
+		{"rice k2 9", func(c *Codec) error { return c.WriteRice(9, 2) }, ReadWith((*Codec).ReadRice, 2), 9, "110 01"},
+		{"rice k0 2", func(c *Codec) error { return c.WriteRice(2, 0) }, ReadWith((*Codec).ReadRice, 0), 2, "110"},
+		{"golomb m3 0", func(c *Codec) error { return c.WriteGolomb(0, 3) }, ReadWith((*Codec).ReadGolomb, 3), 0, "0 0"},
+		{"golomb m3 1", func(c *Codec) error { return c.WriteGolomb(1, 3) }, ReadWith((*Codec).ReadGolomb, 3), 1, "0 10"},
+		{"golomb m3 5", func(c *Codec) error { return c.WriteGolomb(5, 3) }, ReadWith((*Codec).ReadGolomb, 3), 5, "10 11"},
+		{"golomb m5 3", func(c *Codec) error { return c.WriteGolomb(3, 5) }, ReadWith((*Codec).ReadGolomb, 5), 3, "0 110"},
+		{"golomb m1 2", func(c *Codec) error { return c.WriteGolomb(2, 1) }, ReadWith((*Codec).ReadGolomb, 1), 2, "110"},
+	}
+	// Codes are bit sequences, so both bit orders produce the same sequence of bits
+	for _, order := range BitOrders {
+		for _, tt := range tests {
+			t.Run(tt.name+"/"+order.name, func(t *testing.T) {
+				writer := CreateWriter(WithBitOrder(order.order))
+				if err := tt.write(writer); err != nil {
+					t.Fatalf("write error: %v", err)
+				}
+				ExpectBits(t, writer, tt.bits)
+				reader := CreateReader(writer.Buff, WithBitOrder(order.order))
+				value, err := tt.read(reader)
+				if err != nil {
+					t.Fatalf("read error: %v", err)
+				}
+				if value != tt.value {
+					t.Errorf("mismatch: wrote %d, read %d", tt.value, value)
+				}
+				if reader.NumRead() != writer.NumWritten() {
+					t.Errorf("unexpected bits read: got %d, want %d", reader.NumRead(), writer.NumWritten())
+				}
+			})
+		}
+	}
+}
+
+// BitOrders lists both bit orders for tests that run in each.
+var BitOrders = []struct {
+	name  string
+	order BitOrder
+}{{"msb", MSBFirst}, {"lsb", LSBFirst}}
+
+func ReadAs(fn func(*Codec) (uint64, error)) func(*Codec) (int64, error) {
+	return func(c *Codec) (int64, error) {
+		value, err := fn(c)
+		return int64(value), err
+	}
+}
+
+func ReadWith[T uint8 | uint64](fn func(*Codec, T) (uint64, error), param T) func(*Codec) (int64, error) {
+	return func(c *Codec) (int64, error) {
+		value, err := fn(c, param)
+		return int64(value), err
+	}
+}
+
+func TestVariableLengthRoundTrip(t *testing.T) {
+	var (
+		rng      = rand.New(rand.NewSource(time.Now().UnixNano()))
+		unsigned = []uint64{1, 2, 3, 255, 256, math.MaxUint64 - 1}
+		signed   = []int64{0, 1, -1, math.MaxInt64, math.MinInt64 + 1}
+	)
+	for range 100 {
+		unsigned = append(unsigned, max(1, rng.Uint64()>>uint(rng.Intn(64))))
+		signed = append(signed, rng.Int63()>>uint(rng.Intn(63))*int64(1-2*rng.Intn(2)))
+	}
+	for _, order := range BitOrders {
+		RoundTripCodes(t, order.order, unsigned, signed)
+	}
+}
+
+// RoundTripCodes writes every variable length code of the values and reads them back.
+func RoundTripCodes(t *testing.T, order BitOrder, unsigned []uint64, signed []int64) {
+	t.Helper()
+	writer := CreateWriter(WithBitOrder(order))
+	for _, value := range unsigned {
+		if err := writer.WriteExpGolomb(value); err != nil {
+			t.Fatalf("ue write error: %v", err)
+		}
+		if err := writer.WriteEliasGamma(value); err != nil {
+			t.Fatalf("gamma write error: %v", err)
+		}
+		if err := writer.WriteEliasDelta(value); err != nil {
+			t.Fatalf("delta write error: %v", err)
+		}
+		if err := writer.WriteRice(value&0xFFFF, 10); err != nil {
+			t.Fatalf("rice write error: %v", err)
+		}
+		if err := writer.WriteGolomb(value&0xFFFF, 1000); err != nil {
+			t.Fatalf("golomb write error: %v", err)
+		}
+	}
+	for _, value := range signed {
+		if err := writer.WriteSignedExpGolomb(value); err != nil {
+			t.Fatalf("se write error: %v", err)
+		}
+	}
+	reader := CreateReader(writer.Buff, WithBitOrder(order))
+	for _, value := range unsigned {
+		if temp, err := reader.ReadExpGolomb(); err != nil || temp != value {
+			t.Errorf("ue mismatch: wrote %d, read %d, err: %v", value, temp, err)
+		}
+		if temp, err := reader.ReadEliasGamma(); err != nil || temp != value {
+			t.Errorf("gamma mismatch: wrote %d, read %d, err: %v", value, temp, err)
+		}
+		if temp, err := reader.ReadEliasDelta(); err != nil || temp != value {
+			t.Errorf("delta mismatch: wrote %d, read %d, err: %v", value, temp, err)
+		}
+		if temp, err := reader.ReadRice(10); err != nil || temp != value&0xFFFF {
+			t.Errorf("rice mismatch: wrote %d, read %d, err: %v", value&0xFFFF, temp, err)
+		}
+		if temp, err := reader.ReadGolomb(1000); err != nil || temp != value&0xFFFF {
+			t.Errorf("golomb mismatch: wrote %d, read %d, err: %v", value&0xFFFF, temp, err)
+		}
+	}
+	for _, value := range signed {
+		if temp, err := reader.ReadSignedExpGolomb(); err != nil || temp != value {
+			t.Errorf("se mismatch: wrote %d, read %d, err: %v", value, temp, err)
+		}
+	}
+	if reader.NumRead() != writer.NumWritten() {
+		t.Errorf("unexpected bits read: got %d, want %d", reader.NumRead(), writer.NumWritten())
+	}
+}
+
+func TestVariableLengthErrors(t *testing.T) {
+	writer := CreateWriter()
+	if err := writer.WriteEliasGamma(0); err == nil {
+		t.Errorf("expected error for elias gamma of zero")
+	}
+	if err := writer.WriteExpGolomb(math.MaxUint64); err == nil {
+		t.Errorf("expected error for exp-golomb overflow")
+	}
+	if err := writer.WriteGolomb(1, 0); err == nil {
+		t.Errorf("expected error for zero golomb divisor")
+	}
+	reader := CreateReader(make([]byte, 9))
+	if _, err := reader.ReadExpGolomb(); err == nil {
+		t.Errorf("expected error for overlong exp-golomb code")
+	}
+}
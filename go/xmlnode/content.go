@@ -0,0 +1,179 @@
+package xmlnode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Comment represents an XML comment
+type Comment string
+
+func (c Comment) isContent() NodeType {
+	return TypeComment
+}
+
+// ProcInst represents a processing instruction such as <?target inst?>
+type ProcInst struct {
+	Target string
+	Inst   string
+}
+
+func (p ProcInst) isContent() NodeType {
+	return TypeProcInst
+}
+
+// CData represents a CDATA section, kept apart from Text so it is written back as a section
+type CData string
+
+func (c CData) isContent() NodeType {
+	return TypeCData
+}
+
+// Directive represents a directive such as <!DOCTYPE ...>, which only appears outside the root
+type Directive string
+
+func (d Directive) isContent() NodeType {
+	return TypeDirective
+}
+
+// Mixed represents content interleaving elements with text, comments, processing instructions
+// and CDATA sections, in document order. Text items are kept verbatim, so the indentation added
+// by pretty printing alters it.
+type Mixed []Content
+
+func (m Mixed) isContent() NodeType {
+	return TypeMixed
+}
+
+func (n *Node) isContent() NodeType {
+	return TypeElement
+}
+
+// decoder wraps xml.Decoder with the source document when it is known, since the decoder
+// reports CDATA sections as plain character data
+type decoder struct {
+	*xml.Decoder
+	source []byte
+}
+
+var cdataStart = []byte("<![CDATA[")
+
+// token returns the next token, character data being converted to Text or CData
+func (d *decoder) token() (xml.Token, Content, error) {
+	offset := d.InputOffset()
+	token, err := d.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if data, ok := token.(xml.CharData); ok {
+		if offset >= 0 && offset < int64(len(d.source)) && bytes.HasPrefix(d.source[offset:], cdataStart) {
+			return token, CData(data), nil
+		}
+		return token, Text(data), nil
+	}
+	return token, nil, nil
+}
+
+// simplify returns the content collected for an element: Children or Text when the element holds
+// only one kind, Mixed otherwise. Whitespace-only text is formatting and is dropped, unless the
+// element also holds other text, where it separates words.
+func simplify(items Mixed) Content {
+	var (
+		kept     Mixed
+		children Children
+		text     strings.Builder
+		mixed    bool
+		words    bool
+	)
+	for _, item := range items {
+		if t, ok := item.(Text); ok && strings.TrimSpace(string(t)) != "" {
+			words = true
+		}
+	}
+	for _, item := range items {
+		switch item := item.(type) {
+		case Text:
+			text.WriteString(string(item))
+			if !words && strings.TrimSpace(string(item)) == "" {
+				continue
+			}
+		case *Node:
+			children = append(children, item)
+		default:
+			mixed = true
+		}
+		kept = append(kept, item)
+	}
+	switch {
+	case len(kept) == 0:
+		return nil
+	case !mixed && len(children) == len(kept):
+		return children
+	case !mixed && len(children) == 0:
+		return Text(strings.TrimSpace(text.String()))
+	}
+	return kept
+}
+
+// encoder wraps xml.Encoder with its underlying writer when it is known, since the encoder has no
+// CDATA token
+type encoder struct {
+	*xml.Encoder
+	raw    io.Writer
+	sorted bool
+}
+
+// encode writes a content item other than an element
+func (e *encoder) encode(item Content) error {
+	switch item := item.(type) {
+	case Text:
+		if len(item) > 0 {
+			return e.EncodeToken(xml.CharData(item))
+		}
+	case CData:
+		// Without the writer the section is written as the equivalent escaped text. A "]]>" inside
+		// the data splits the section.
+		if e.raw == nil {
+			return e.EncodeToken(xml.CharData(item))
+		}
+		if err := e.Flush(); err != nil {
+			return err
+		}
+		_, err := io.WriteString(e.raw, "<![CDATA["+strings.ReplaceAll(string(item), "]]>", "]]]]><![CDATA[>")+"]]>")
+		return err
+	case Comment:
+		return e.EncodeToken(xml.Comment(item))
+	case ProcInst:
+		return e.EncodeToken(xml.ProcInst{Target: item.Target, Inst: []byte(item.Inst)})
+	case Directive:
+		return e.EncodeToken(xml.Directive(item))
+	}
+	return nil
+}
+
+// text returns the concatenated text and CDATA items of mixed content
+func (m Mixed) text() string {
+	var text strings.Builder
+	for _, item := range m {
+		switch item := item.(type) {
+		case Text:
+			text.WriteString(string(item))
+		case CData:
+			text.WriteString(string(item))
+		}
+	}
+	return text.String()
+}
+
+// elements returns the element items of mixed content
+func (m Mixed) elements() Children {
+	var children Children
+	for _, item := range m {
+		if child, ok := item.(*Node); ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
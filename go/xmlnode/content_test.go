@@ -0,0 +1,132 @@
+package xmlnode
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+const VENDOR_CONFIG = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- generated by vendor tool -->
+<!DOCTYPE config>
+<config xmlns="urn:vendor">
+  <!-- management interface -->
+  <interface name="mgmt0">
+    <description>Uplink to <b>core</b> switch</description>
+    <script><![CDATA[if (a < b && c > "d") { run(); }]]></script>
+    <?vendor-hint keep?>
+  </interface>
+</config>
+<!-- end -->
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := ParseDocument([]byte(VENDOR_CONFIG))
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- generated by vendor tool -->
+<!DOCTYPE config>
+<config xmlns="urn:vendor"><!-- management interface --><interface name="mgmt0">` +
+		`<description>Uplink to <b>core</b> switch</description>` +
+		`<script><![CDATA[if (a < b && c > "d") { run(); }]]></script>` +
+		`<?vendor-hint keep?></interface></config>
+<!-- end -->`
+	out, err := doc.ToXML(false)
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if out != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+
+	// Writing the document out again does not change it
+	again, err := ParseDocument([]byte(out))
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if out2, _ := again.ToXML(false); out2 != out {
+		t.Errorf("second round trip differs:\n%s", out2)
+	}
+}
+
+func TestMixedContent(t *testing.T) {
+	doc, err := ParseDocument([]byte(VENDOR_CONFIG))
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	iface := doc.Root.FindFirst("interface")
+	if iface == nil || iface.GetAttribute("name") != "mgmt0" {
+		t.Fatalf("interface not found among %v", doc.Root.Content)
+	}
+	tests := []struct {
+		node *Node
+		kind NodeType
+		text string
+	}{
+		{doc.Root, TypeMixed, ""},
+		{iface.FindFirst("description"), TypeMixed, "Uplink to  switch"},
+		{iface.FindFirst("script"), TypeMixed, `if (a < b && c > "d") { run(); }`},
+		{iface.FindFirst("description").FindFirst("b"), TypeText, "core"},
+	}
+	for _, test := range tests {
+		if kind := test.node.Content.isContent(); kind != test.kind {
+			t.Errorf("%s: content type %d, want %d", test.node.Name, kind, test.kind)
+		}
+		if text := test.node.GetText(); text != test.text {
+			t.Errorf("%s: text %q, want %q", test.node.Name, text, test.text)
+		}
+	}
+	if items := iface.Content.(Mixed); len(items) != 3 || items[2] != Content(ProcInst{Target: "vendor-hint", Inst: "keep"}) {
+		t.Errorf("unexpected interface content %#v", items)
+	}
+
+	// Mixed content stays editable through the element accessors
+	iface.AddChild(&Node{Name: "mtu", Content: Text("1500")})
+	if iface.FindFirst("mtu") == nil || len(iface.GetALL()) != 3 {
+		t.Errorf("child not added to mixed content: %#v", iface.Content)
+	}
+	if iface.RemoveChildrenByName("script") != 1 || len(iface.Content.(Mixed)) != 3 {
+		t.Errorf("child not removed from mixed content: %#v", iface.Content)
+	}
+}
+
+func TestMixedContentSpacing(t *testing.T) {
+	// Whitespace between inline elements is kept once the element holds words
+	const para = `<p>Hello <b>big</b> <i>world</i></p>`
+	root := new(Node)
+	if err := root.FromXML([]byte(para)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if out, err := root.ToXML(false); err != nil || out != para {
+		t.Errorf("got %s, want %s, err: %v", out, para, err)
+	}
+	if items, ok := root.Content.(Mixed); !ok || len(items) != 4 || items[2] != Content(Text(" ")) {
+		t.Errorf("unexpected content %#v", root.Content)
+	}
+}
+
+func TestMixedContentUnmarshal(t *testing.T) {
+	// Through encoding/xml the source is not known, so CDATA sections are read as text
+	node := new(Node)
+	if err := xml.Unmarshal([]byte(`<p>Hello <![CDATA[<world>]]><b>!</b><!--c--></p>`), node); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	want := Mixed{Text("Hello "), Text("<world>"), node.FindFirst("b"), Comment("c")}
+	content, ok := node.Content.(Mixed)
+	if !ok || len(content) != len(want) {
+		t.Fatalf("got %#v", node.Content)
+	}
+	for i := range want {
+		if content[i] != want[i] {
+			t.Errorf("item %d: got %#v, want %#v", i, content[i], want[i])
+		}
+	}
+	out, err := xml.Marshal(node)
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if string(out) != `<p>Hello &lt;world&gt;<b>!</b><!--c--></p>` {
+		t.Errorf("got %s", out)
+	}
+}
@@ -0,0 +1,101 @@
+package xmlnode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Document is a parsed XML document: the root element with the XML declaration, comments,
+// processing instructions and directives before and after it
+type Document struct {
+	Prolog Mixed
+	Root   *Node
+	Epilog Mixed
+}
+
+// ParseDocument parses a complete XML document
+func ParseDocument(data []byte) (*Document, error) {
+	var (
+		doc = new(Document)
+		d   = &decoder{Decoder: xml.NewDecoder(bytes.NewReader(data)), source: data}
+	)
+	for {
+		token, _, err := d.token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var item Content
+		switch t := token.(type) {
+		case xml.StartElement:
+			if doc.Root != nil {
+				return nil, errors.New("document has more than one root element")
+			}
+			doc.Root = new(Node)
+			if err := doc.Root.unmarshal(d, t, nil); err != nil {
+				return nil, err
+			}
+			continue
+		case xml.Comment:
+			item = Comment(t)
+		case xml.ProcInst:
+			item = ProcInst{Target: t.Target, Inst: string(t.Inst)}
+		case xml.Directive:
+			item = Directive(t)
+		default:
+			// Whitespace outside the root element
+			continue
+		}
+		if doc.Root == nil {
+			doc.Prolog = append(doc.Prolog, item)
+		} else {
+			doc.Epilog = append(doc.Epilog, item)
+		}
+	}
+	if doc.Root == nil {
+		return nil, errors.New("document has no root element")
+	}
+	return doc, nil
+}
+
+// ToXML writes the document, each item outside the root element on a line of its own
+func (doc *Document) ToXML(pretty bool, opts ...Option) (string, error) {
+	if doc == nil || doc.Root == nil {
+		return "", errors.New("document has no root element")
+	}
+	var buf bytes.Buffer
+	e := &encoder{Encoder: xml.NewEncoder(&buf), raw: &buf}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if pretty {
+		e.Indent("", "  ")
+	}
+	for _, item := range doc.Prolog {
+		if err := e.encode(item); err != nil {
+			return "", err
+		}
+		if err := e.EncodeToken(xml.CharData("\n")); err != nil {
+			return "", err
+		}
+	}
+	if err := doc.Root.marshal(e, nil); err != nil {
+		return "", err
+	}
+	for _, item := range doc.Epilog {
+		if err := e.EncodeToken(xml.CharData("\n")); err != nil {
+			return "", err
+		}
+		if err := e.encode(item); err != nil {
+			return "", err
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,136 @@
+package xmlnode
+
+import "strings"
+
+// XMLNamespace is the namespace permanently bound to the "xml" prefix
+const XMLNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// binding maps a prefix to a namespace URI, the empty prefix being the default namespace
+type binding struct {
+	prefix string
+	uri    string
+}
+
+// scope is the stack of in-scope namespace bindings, innermost last
+type scope []binding
+
+// push returns the scope extended with a binding, never sharing storage with sibling scopes
+func (s scope) push(prefix, uri string) scope {
+	return append(s[:len(s):len(s)], binding{prefix: prefix, uri: uri})
+}
+
+// uri returns the namespace bound to prefix
+func (s scope) uri(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return XMLNamespace, true
+	}
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i].prefix == prefix {
+			return s[i].uri, true
+		}
+	}
+	return "", false
+}
+
+// prefix returns the innermost prefix bound to uri that is not shadowed by a later binding. The
+// default namespace is only considered for element names, as it does not apply to attributes,
+// and is preferred for them since the decoder does not report whether a name had a prefix.
+func (s scope) prefix(uri string, element bool) (string, bool) {
+	if uri == XMLNamespace {
+		return "xml", true
+	}
+	if bound, ok := s.uri(""); element && ok && bound == uri {
+		return "", true
+	}
+	for i := len(s) - 1; i >= 0; i-- {
+		b := s[i]
+		if b.uri != uri || (b.prefix == "" && !element) {
+			continue
+		}
+		if bound, _ := s.uri(b.prefix); bound == uri {
+			return b.prefix, true
+		}
+	}
+	return "", false
+}
+
+// resolve returns the namespace URI of an attribute key such as "prefix:local", or the prefix
+// itself when it is not bound. Unprefixed attributes have no namespace.
+func (s scope) resolve(key string) (space, local string) {
+	colon := strings.Index(key, ":")
+	if colon < 0 {
+		return "", key
+	}
+	space, local = key[:colon], key[colon+1:]
+	if uri, ok := s.uri(space); ok {
+		space = uri
+	}
+	return space, local
+}
+
+// IsNamespaceDeclaration reports whether an attribute key is an "xmlns" or "xmlns:prefix" declaration
+func IsNamespaceDeclaration(key string) bool {
+	return key == "xmlns" || strings.HasPrefix(key, "xmlns:")
+}
+
+// Declarations returns the namespace bindings declared on the node, keyed by prefix with the
+// empty prefix for the default namespace
+func (n *Node) Declarations() map[string]string {
+	if n == nil {
+		return nil
+	}
+	declarations := make(map[string]string)
+	for key, value := range n.Attributes {
+		if IsNamespaceDeclaration(key) {
+			declarations[strings.TrimPrefix(strings.TrimPrefix(key, "xmlns"), ":")] = value
+		}
+	}
+	return declarations
+}
+
+// declarationKey returns the attribute key declaring prefix, "xmlns" for the default namespace
+func declarationKey(prefix string) string {
+	if prefix == "" {
+		return "xmlns"
+	}
+	return "xmlns:" + prefix
+}
+
+// Declare adds an "xmlns" or "xmlns:prefix" declaration to the node
+func (n *Node) Declare(prefix, uri string) {
+	n.SetAttribute(declarationKey(prefix), uri)
+}
+
+// declare returns the scope extended with the declarations of the node, and with the binding of
+// its own prefix when the node carries a namespace its declarations do not provide
+func (n *Node) declare(s scope) scope {
+	for prefix, uri := range n.Declarations() {
+		s = s.push(prefix, uri)
+	}
+	if n.Space != "" {
+		if uri, ok := s.uri(n.Prefix); !ok || uri != n.Space {
+			s = s.push(n.Prefix, n.Space)
+		}
+	}
+	return s
+}
+
+// Namespace returns the namespace URI of the node. For nodes built without a resolved Space, the
+// default namespace declared on the node itself is used.
+func (n *Node) Namespace() string {
+	if n == nil {
+		return ""
+	}
+	if n.Space == "" && n.Prefix == "" {
+		return n.Attributes["xmlns"]
+	}
+	return n.Space
+}
+
+// QualifiedName returns the name of the node with its prefix, as written in the document
+func (n *Node) QualifiedName() string {
+	if n == nil {
+		return ""
+	}
+	return MakeName(n.Prefix, n.Name)
+}
@@ -0,0 +1,145 @@
+package xmlnode
+
+import (
+	"strings"
+	"testing"
+)
+
+const RPC = `<nc:rpc xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="101">
+  <nc:get>
+    <nc:filter nc:type="subtree">
+      <top xmlns="http://example.com/schema/1.2/config" xml:lang="en"/>
+    </nc:filter>
+  </nc:get>
+</nc:rpc>`
+
+func TestNamespaceResolution(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(RPC)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	const base = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	filter := root.FindFirst("get").FindFirst("filter")
+	top := filter.FindFirst("top")
+	tests := []struct {
+		node           *Node
+		space, prefix  string
+		attribute, val string
+	}{
+		{root, base, "nc", "message-id", "101"},
+		{filter, base, "nc", "nc:type", "subtree"},
+		{top, "http://example.com/schema/1.2/config", "", "xml:lang", "en"},
+	}
+	for _, test := range tests {
+		if test.node.Space != test.space || test.node.Prefix != test.prefix {
+			t.Errorf("%s: got namespace %q prefix %q", test.node.Name, test.node.Space, test.node.Prefix)
+		}
+		if got := test.node.GetAttribute(test.attribute); got != test.val {
+			t.Errorf("%s: attribute %s is %q, want %q", test.node.Name, test.attribute, got, test.val)
+		}
+	}
+
+	// The document is written back with its prefixes and declarations, and parses to the same names
+	out, err := root.ToXML(false)
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	for _, want := range []string{`<nc:rpc xmlns:nc="` + base + `"`, `<nc:filter nc:type="subtree">`, `<top xmlns="http://example.com/schema/1.2/config" xml:lang="en"></top>`, `</nc:rpc>`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %s does not contain %s", out, want)
+		}
+	}
+	if strings.Count(out, "xmlns:nc") != 1 {
+		t.Errorf("prefix declared more than once: %s", out)
+	}
+	again := new(Node)
+	if err := again.FromXML([]byte(out)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if got := again.FindFirst("get").FindFirst("filter").FindFirst("top").GetXMLName(); got != top.GetXMLName() {
+		t.Errorf("round trip name mismatch: %v", got)
+	}
+}
+
+func TestNamespaceDeclaration(t *testing.T) {
+	// Nodes built in code declare their namespace where it is not already in scope
+	root := &Node{Name: "config", Space: "urn:a", Prefix: "a"}
+	root.AddChild(&Node{Name: "item", Space: "urn:a", Prefix: "a"})
+	root.AddChild(&Node{Name: "item", Space: "urn:b"})
+	root.AddChild(&Node{Name: "plain"})
+	out, err := root.ToXML(false)
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	want := `<a:config xmlns:a="urn:a"><a:item></a:item><item xmlns="urn:b"></item><plain></plain></a:config>`
+	if out != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+}
+
+func TestNamespaceDefaultAndPrefix(t *testing.T) {
+	// An unprefixed element keeps the default namespace when a prefix is bound to the same URI
+	root := new(Node)
+	if err := root.FromXML([]byte(`<a xmlns="urn:u" xmlns:p="urn:u"><b/><p:c/></a>`)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if b := root.FindFirst("b"); root.Prefix != "" || b.Prefix != "" || b.Space != "urn:u" {
+		t.Errorf("unexpected prefixes %q and %q", root.Prefix, b.Prefix)
+	}
+	out, err := root.ToXML(false)
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	if !strings.HasPrefix(out, `<a `) || !strings.Contains(out, `<b></b>`) || !strings.HasSuffix(out, `</a>`) {
+		t.Errorf("prefix added to unprefixed names: %s", out)
+	}
+}
+
+const INTERFACES = `
+<top xmlns="http://example.com/schema/1.2/config" xmlns:x="urn:extra">
+  <interfaces xmlns="urn:vendor-a">
+    <interface x:enabled="true"><name>eth0</name></interface>
+  </interfaces>
+  <interfaces xmlns="urn:vendor-b">
+    <interface><name>ge-0/0/0</name></interface>
+  </interfaces>
+</top>
+`
+
+func TestSubtreeFilterNamespaces(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(INTERFACES)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	tests := []struct {
+		name   string
+		filter string
+		want   []string // Interface names selected
+	}{
+		{"vendor a", `<top xmlns="http://example.com/schema/1.2/config"><interfaces xmlns="urn:vendor-a"/></top>`, []string{"eth0"}},
+		{"vendor b", `<top xmlns="http://example.com/schema/1.2/config"><interfaces xmlns="urn:vendor-b"/></top>`, []string{"ge-0/0/0"}},
+		{"any namespace", `<top xmlns="http://example.com/schema/1.2/config"><interfaces xmlns=""/></top>`, []string{"eth0", "ge-0/0/0"}},
+		{"wrong namespace", `<top xmlns="urn:other"><interfaces/></top>`, nil},
+		{"attribute by namespace", `<top xmlns="http://example.com/schema/1.2/config" xmlns:y="urn:extra"><interfaces xmlns=""><interface y:enabled="true"/></interfaces></top>`, []string{"eth0"}},
+		{"attribute wrong namespace", `<top xmlns="http://example.com/schema/1.2/config" xmlns:y="urn:other"><interfaces xmlns=""><interface y:enabled="true"/></interfaces></top>`, nil},
+	}
+	for _, test := range tests {
+		filter := new(Node)
+		if err := filter.FromXML([]byte(test.filter)); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		filtered := root.SubtreeFilter(filter)
+		var got []string
+		for _, interfaces := range filtered.GetNodes("interfaces") {
+			for _, iface := range interfaces.GetNodes("interface") {
+				got = append(got, iface.FindFirst("name").GetText())
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s: selected %v, want %v", test.name, got, test.want)
+		}
+	}
+	if !root.MatchAttributes(nil) {
+		t.Errorf("a nil filter must match")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 )
 
@@ -14,9 +16,18 @@ type NodeType int
 const (
 	TypeText NodeType = iota
 	TypeChildren
+	TypeMixed
+	TypeElement
+	TypeComment
+	TypeProcInst
+	TypeCData
+	TypeDirective
+	TypeAttribute // XPath attribute nodes
+	TypeRoot      // XPath root node
 )
 
-// Content is an interface for node content (either text or children)
+// Content is an interface for node content: text, children or mixed content, and the items of
+// mixed content
 type Content interface {
 	isContent() NodeType // Returns the type of content
 }
@@ -35,11 +46,18 @@ func (c Children) isContent() NodeType {
 	return TypeChildren
 }
 
-// Node represents an XML node with attributes and content
+// Node represents an XML node with attributes and content. Space holds the resolved namespace URI
+// and Prefix the prefix the name was written with; namespace declarations are kept in Attributes
+// as "xmlns" and "xmlns:prefix" entries, and other attributes are keyed by their prefixed name.
+// Attributes are written in source order, then in SetAttribute order, and any key added to the map
+// directly comes last in sorted order.
 type Node struct {
 	Name       string
+	Space      string
+	Prefix     string
 	Attributes map[string]string
 	Content    Content
+	order      []string // Attribute keys in source and insertion order
 }
 
 // MakeXMLName creates an xml.Name from a string with optional namespace
@@ -71,8 +89,7 @@ func MakeName(space, local string) string {
 }
 
 func (n *Node) GetXMLName() xml.Name {
-	space := n.Attributes["xmlns"]
-	return xml.Name{Space: space, Local: n.Name}
+	return xml.Name{Space: n.Namespace(), Local: n.Name}
 }
 
 func PrintXMLName(name xml.Name) {
@@ -104,6 +121,9 @@ func (n *Node) SetAttribute(key, value string) {
 	if n.Attributes == nil {
 		n.Attributes = make(map[string]string)
 	}
+	if _, ok := n.Attributes[key]; !ok {
+		n.order = append(n.order, key)
+	}
 	n.Attributes[key] = value
 }
 
@@ -112,6 +132,7 @@ func (n *Node) RemoveAttribute(key string) {
 		return
 	}
 	delete(n.Attributes, key)
+	n.order = slices.DeleteFunc(n.order, func(k string) bool { return k == key })
 }
 
 func (n *Node) ClearAttributes() {
@@ -119,19 +140,83 @@ func (n *Node) ClearAttributes() {
 		return
 	}
 	n.Attributes = nil
+	n.order = nil
+}
+
+// AttributeKeys returns the attribute keys in the order they are written: source and insertion
+// order, followed by the keys added to the map directly, sorted
+func (n *Node) AttributeKeys() []string {
+	if n == nil || n.Attributes == nil {
+		return nil
+	}
+	var (
+		keys  = make([]string, 0, len(n.Attributes))
+		known = make(map[string]bool, len(n.order))
+		rest  []string
+	)
+	for _, key := range n.order {
+		if _, ok := n.Attributes[key]; ok && !known[key] {
+			keys = append(keys, key)
+			known[key] = true
+		}
+	}
+	for key := range n.Attributes {
+		if !known[key] {
+			rest = append(rest, key)
+		}
+	}
+	slices.Sort(rest)
+	return append(keys, rest...)
+}
+
+// sortedAttributeKeys returns the namespace declarations sorted by key, followed by the other
+// attributes sorted by key
+func (n *Node) sortedAttributeKeys() []string {
+	keys := slices.Collect(maps.Keys(n.Attributes))
+	slices.SortFunc(keys, func(a, b string) int {
+		if IsNamespaceDeclaration(a) != IsNamespaceDeclaration(b) {
+			if IsNamespaceDeclaration(a) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	return keys
 }
 
-func (n *Node) ToXML(pretty bool) (string, error) {
+// copyAttributes returns a copy of the attributes of the node and their order
+func (n *Node) copyAttributes() (map[string]string, []string) {
+	return maps.Clone(n.Attributes), slices.Clone(n.order)
+}
+
+// Option configures how a Node or Document is written
+type Option func(*encoder)
+
+// WithSortedAttributes writes namespace declarations and then attributes in sorted key order
+// rather than in source order
+func WithSortedAttributes() Option {
+	return func(e *encoder) {
+		e.sorted = true
+	}
+}
+
+func (n *Node) ToXML(pretty bool, opts ...Option) (string, error) {
 	if n == nil {
 		return "", nil
 	}
 	var buf bytes.Buffer
-	encoder := xml.NewEncoder(&buf)
+	e := &encoder{Encoder: xml.NewEncoder(&buf), raw: &buf}
+	for _, opt := range opts {
+		opt(e)
+	}
 	if pretty {
-		encoder.Indent("", "  ")
+		e.Indent("", "  ")
 	}
-	err := encoder.Encode(n)
-	if err != nil {
+	if err := n.marshal(e, nil); err != nil {
+		return "", err
+	}
+	if err := e.Flush(); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -141,44 +226,55 @@ func (n *Node) FromXML(data []byte) error {
 	if n == nil {
 		return errors.New("node is nil")
 	}
-	decoder := xml.NewDecoder(bytes.NewReader(data))
-	return decoder.Decode(n)
+	d := &decoder{Decoder: xml.NewDecoder(bytes.NewReader(data)), source: data}
+	for {
+		token, _, err := d.token()
+		if err != nil {
+			return err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return n.unmarshal(d, start, nil)
+		}
+	}
+}
+
+// elements returns the child elements of the node, whether its content is Children or Mixed
+func (n *Node) elements() Children {
+	switch content := n.Content.(type) {
+	case Children:
+		return content
+	case Mixed:
+		return content.elements()
+	}
+	return nil
 }
 
 func (n *Node) GetNodes(name string) []*Node {
 	if n == nil {
 		return nil
 	}
-	if children, ok := n.Content.(Children); ok {
-		var result []*Node
-		for _, child := range children {
-			if child.Name == name {
-				result = append(result, child)
-			}
+	var result []*Node
+	for _, child := range n.elements() {
+		if child.Name == name {
+			result = append(result, child)
 		}
-		return result
 	}
-	return nil
+	return result
 }
 
 func (n *Node) GetALL() []*Node {
 	if n == nil {
 		return nil
 	}
-	if children, ok := n.Content.(Children); ok {
-		return children
-	}
-	return nil
+	return n.elements()
 }
 
 func (n *Node) WalkNodes(fn func(*Node)) {
 	if n == nil || fn == nil {
 		return
 	}
-	if children, ok := n.Content.(Children); ok {
-		for _, child := range children {
-			fn(child)
-		}
+	for _, child := range n.elements() {
+		fn(child)
 	}
 }
 
@@ -186,11 +282,9 @@ func (n *Node) FindFirst(name string) *Node {
 	if n == nil {
 		return nil
 	}
-	if children, ok := n.Content.(Children); ok {
-		for _, child := range children {
-			if child.Name == name {
-				return child
-			}
+	for _, child := range n.elements() {
+		if child.Name == name {
+			return child
 		}
 	}
 	return nil
@@ -200,18 +294,18 @@ func (n *Node) HasChildren() bool {
 	if n == nil {
 		return false
 	}
-	if children, ok := n.Content.(Children); ok && len(children) > 0 {
-		return true
-	}
-	return false
+	return len(n.elements()) > 0
 }
 
 func (n *Node) GetText() string {
 	if n == nil {
 		return ""
 	}
-	if text, ok := n.Content.(Text); ok {
-		return string(text)
+	switch content := n.Content.(type) {
+	case Text:
+		return string(content)
+	case Mixed:
+		return content.text()
 	}
 	return ""
 }
@@ -232,19 +326,19 @@ func (n *Node) HasText() bool {
 	if n == nil {
 		return false
 	}
-	if text, ok := n.Content.(Text); ok && len(text) > 0 {
-		return true
-	}
-	return false
+	return len(n.GetText()) > 0
 }
 
 func (n *Node) AddChild(child *Node) {
 	if n == nil || child == nil {
 		return
 	}
-	if children, ok := n.Content.(Children); ok {
-		n.Content = append(children, child)
-	} else {
+	switch content := n.Content.(type) {
+	case Children:
+		n.Content = append(content, child)
+	case Mixed:
+		n.Content = append(content, child)
+	default:
 		n.Content = Children{child}
 	}
 }
@@ -253,10 +347,18 @@ func (n *Node) RemoveChild(child *Node) bool {
 	if n == nil || child == nil {
 		return false
 	}
-	if children, ok := n.Content.(Children); ok {
-		for i, c := range children {
+	switch content := n.Content.(type) {
+	case Children:
+		for i, c := range content {
 			if c == child {
-				n.Content = append(children[:i], children[i+1:]...)
+				n.Content = append(content[:i], content[i+1:]...)
+				return true
+			}
+		}
+	case Mixed:
+		for i, c := range content {
+			if c == Content(child) {
+				n.Content = append(content[:i], content[i+1:]...)
 				return true
 			}
 		}
@@ -268,12 +370,13 @@ func (n *Node) RemoveChildrenByName(name string) int {
 	if n == nil {
 		return 0
 	}
-	if children, ok := n.Content.(Children); ok {
+	switch content := n.Content.(type) {
+	case Children:
 		var (
 			temp  Children
 			count int
 		)
-		for _, c := range children {
+		for _, c := range content {
 			if c.Name != name {
 				temp = append(temp, c)
 			} else {
@@ -282,6 +385,20 @@ func (n *Node) RemoveChildrenByName(name string) int {
 		}
 		n.Content = temp
 		return count
+	case Mixed:
+		var (
+			temp  Mixed
+			count int
+		)
+		for _, c := range content {
+			if child, ok := c.(*Node); ok && child.Name == name {
+				count++
+			} else {
+				temp = append(temp, c)
+			}
+		}
+		n.Content = temp
+		return count
 	}
 	return 0
 }
@@ -290,10 +407,18 @@ func (n *Node) ReplaceChild(with, what *Node) bool {
 	if n == nil || with == nil || what == nil {
 		return false
 	}
-	if children, ok := n.Content.(Children); ok {
-		for i, c := range children {
+	switch content := n.Content.(type) {
+	case Children:
+		for i, c := range content {
 			if c == with {
-				children[i] = what
+				content[i] = what
+				return true
+			}
+		}
+	case Mixed:
+		for i, c := range content {
+			if c == Content(with) {
+				content[i] = what
 				return true
 			}
 		}
@@ -306,20 +431,33 @@ func (n *Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	if n == nil {
 		return nil
 	}
-	// Set the element name
-	start.Name = xml.Name{Local: n.Name}
-	if xmlns, ok := n.Attributes["xmlns"]; ok {
-		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: xmlns})
+	return n.marshal(&encoder{Encoder: e}, nil)
+}
+
+// marshal encodes the node with its prefixed name, declaring its namespace when the bindings in
+// scope from the ancestors and its own declarations do not already provide it
+func (n *Node) marshal(e *encoder, parent scope) error {
+	// Names are written as-is so the encoder does not invent prefixes of its own
+	start := xml.StartElement{Name: xml.Name{Local: n.QualifiedName()}}
+	s := parent
+	for prefix, uri := range n.Declarations() {
+		s = s.push(prefix, uri)
+	}
+	// A declaration the node needs but does not carry comes first
+	if n.Space != "" && n.Prefix != "xml" {
+		if uri, ok := s.uri(n.Prefix); !ok || uri != n.Space {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: declarationKey(n.Prefix)}, Value: n.Space})
+			s = s.push(n.Prefix, n.Space)
+		}
 	}
 
 	// Add attributes
-	if n.Attributes != nil {
-		for key, value := range n.Attributes {
-			if key != "xmlns" {
-				attrName := MakeXMLName(key)
-				start.Attr = append(start.Attr, xml.Attr{Name: attrName, Value: value})
-			}
-		}
+	keys := n.AttributeKeys()
+	if e.sorted {
+		keys = n.sortedAttributeKeys()
+	}
+	for _, key := range keys {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: key}, Value: n.Attributes[key]})
 	}
 
 	// Start the element
@@ -329,23 +467,33 @@ func (n *Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 	// Handle content based on type
 	switch content := n.Content.(type) {
-	case Text:
-		text := xml.CharData(content)
-		if len(text) > 0 {
-			if err := e.EncodeToken(text); err != nil {
+	case Children:
+		for _, child := range content {
+			if child == nil {
+				continue
+			}
+			if err := child.marshal(e, s); err != nil {
 				return err
 			}
 		}
-	case Children:
-		if len(content) > 0 {
-			for _, child := range content {
-				if err := e.Encode(child); err != nil {
-					return err
-				}
+	case Mixed:
+		for _, item := range content {
+			var err error
+			if child, ok := item.(*Node); ok {
+				err = child.marshal(e, s)
+			} else {
+				err = e.encode(item)
+			}
+			if err != nil {
+				return err
 			}
 		}
 	case nil:
 		// Do Nothing
+	default:
+		if err := e.encode(content); err != nil {
+			return err
+		}
 	}
 
 	// End the element
@@ -354,22 +502,41 @@ func (n *Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 // UnmarshalXML implements the xml.Unmarshaler interface
 func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	// PrintXMLName(start.Name)
+	return n.unmarshal(&decoder{Decoder: d}, start, nil)
+}
+
+// unmarshal decodes an element whose names the decoder has already resolved to namespace URIs,
+// recovering the prefixes from the bindings in scope
+func (n *Node) unmarshal(d *decoder, start xml.StartElement, parent scope) error {
 	n.Name = start.Name.Local
+	n.Space = start.Name.Space
+	n.Prefix = ""
 	n.Attributes = make(map[string]string)
+	n.order = make([]string, 0, len(start.Attr))
+
+	// Declarations on the element apply to its own name and attributes
+	s := parent
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" {
+			s = s.push(attr.Name.Local, attr.Value)
+		} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			s = s.push("", attr.Value)
+		}
+	}
+	if prefix, ok := s.prefix(n.Space, true); ok {
+		n.Prefix = prefix
+	}
 
 	// Collect attributes
 	for _, attr := range start.Attr {
-		key := XMLNameString(attr.Name)
+		key := attributeKey(attr.Name, s)
 		n.Attributes[key] = attr.Value
+		n.order = append(n.order, key)
 	}
 
-	var (
-		children Children
-		text     Text
-	)
+	var items Mixed
 	for {
-		token, err := d.Token()
+		token, data, err := d.token()
 		if err != nil {
 			return err
 		}
@@ -377,25 +544,36 @@ func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		switch t := token.(type) {
 		case xml.StartElement:
 			// Handle nested elements
-			var child Node
-			if err := d.DecodeElement(&child, &t); err != nil {
+			child := new(Node)
+			if err := child.unmarshal(d, t, s); err != nil {
 				return err
 			}
-			children = append(children, &child)
+			items = append(items, child)
 		case xml.CharData:
-			// Collect character data
-			text += Text(t)
+			// Collect character data as Text or CData
+			items = append(items, data)
+		case xml.Comment:
+			items = append(items, Comment(t))
+		case xml.ProcInst:
+			items = append(items, ProcInst{Target: t.Target, Inst: string(t.Inst)})
 		case xml.EndElement:
 			// Set content based on what was found
-			text = Text(strings.TrimSpace(string(text)))
-			if len(children) > 0 {
-				n.Content = children
-			} else if len(text) > 0 {
-				n.Content = text
-			} else {
-				n.Content = nil
-			}
+			n.Content = simplify(items)
 			return nil
 		}
 	}
 }
+
+// attributeKey returns the key of a resolved attribute name, written back with its prefix
+func attributeKey(name xml.Name, s scope) string {
+	switch name.Space {
+	case "":
+		return name.Local
+	case "xmlns":
+		return "xmlns:" + name.Local
+	}
+	if prefix, ok := s.prefix(name.Space, false); ok {
+		return MakeName(prefix, name.Local)
+	}
+	return MakeName(name.Space, name.Local)
+}
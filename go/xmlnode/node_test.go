@@ -33,3 +33,41 @@ func TestXMLNode(t *testing.T) {
 	}
 	fmt.Println(string(content))
 }
+
+func TestAttributeOrder(t *testing.T) {
+	node := new(Node)
+	if err := node.FromXML([]byte(`<a z="1" xmlns="urn:x" b="2" xmlns:p="urn:p" p:c="3"/>`)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	node.SetAttribute("m", "4")
+	node.SetAttribute("z", "5")
+	node.Attributes["direct"] = "6"
+	node.RemoveAttribute("b")
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"source", nil, `<a z="5" xmlns="urn:x" xmlns:p="urn:p" p:c="3" m="4" direct="6"></a>`},
+		{"sorted", []Option{WithSortedAttributes()}, `<a xmlns="urn:x" xmlns:p="urn:p" direct="6" m="4" p:c="3" z="5"></a>`},
+	}
+	for _, test := range tests {
+		// Map iteration order changes from run to run, so write the node repeatedly
+		for range 20 {
+			out, err := node.ToXML(false, test.opts...)
+			if err != nil {
+				t.Fatal("Error: ", err)
+			}
+			if out != test.want {
+				t.Fatalf("%s: got %s, want %s", test.name, out, test.want)
+			}
+		}
+	}
+
+	// Filtering keeps the order of the data node
+	filtered := node.SubtreeFilter(&Node{Name: "a"})
+	if out, _ := filtered.ToXML(false); out != tests[0].want {
+		t.Errorf("filtered: got %s", out)
+	}
+}
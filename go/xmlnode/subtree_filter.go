@@ -1,44 +1,86 @@
 package xmlnode
 
-import "maps"
-
+// MatchAttributes reports whether the node carries every attribute of the filter, comparing
+// attribute names by namespace URI rather than prefix. Namespace declarations are not matched.
 func (n *Node) MatchAttributes(filter *Node) bool {
-	if filter == nil || filter.Attributes == nil {
+	if filter == nil {
 		return true
 	}
-	if n.Attributes == nil {
-		return false
+	return n.matchAttributes(filter, n.declare(nil), filter.declare(nil))
+}
+
+func (n *Node) matchAttributes(filter *Node, data, selection scope) bool {
+	if filter == nil || filter.Attributes == nil {
+		return true
 	}
 	for key, value := range filter.Attributes {
-		v, ok := n.Attributes[key]
-		if !ok || v != value {
+		if IsNamespaceDeclaration(key) {
+			continue
+		}
+		space, local := selection.resolve(key)
+		found := false
+		for k, v := range n.Attributes {
+			if IsNamespaceDeclaration(k) {
+				continue
+			}
+			if s, l := data.resolve(k); s == space && l == local && v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
 			return false
 		}
 	}
 	return true
 }
 
+// space returns the namespace of the node, inherited from the scope when it has none of its own
+func (n *Node) space(s scope) string {
+	if n.Space != "" {
+		return n.Space
+	}
+	uri, _ := s.uri(n.Prefix)
+	return uri
+}
+
+// SubtreeFilter applies an RFC 6241 subtree filter to the node. Filter elements match data
+// elements of the same name in the same namespace; a filter element without a namespace matches
+// the name in any namespace.
 func (n *Node) SubtreeFilter(filter *Node) *Node {
+	return n.subtreeFilter(filter, nil, nil)
+}
+
+func (n *Node) subtreeFilter(filter *Node, data, selection scope) *Node {
 	if n == nil || filter == nil {
 		return nil
 	}
+	data = n.declare(data)
+	selection = filter.declare(selection)
 
-	// Check if the data node matches the filter node name
+	// Check if the data node matches the filter node name and namespace
 	if n.Name != filter.Name {
 		return nil
 	}
+	if space := filter.space(selection); space != "" && space != n.space(data) {
+		return nil
+	}
 
 	// Check if the data node attributes match the filter attributes
-	if !n.MatchAttributes(filter) {
+	if !n.matchAttributes(filter, data, selection) {
 		return nil
 	}
 
 	// Create a result node with the same name and attributes
 	result := &Node{
-		Name:       n.Name,
-		Attributes: make(map[string]string),
+		Name:   n.Name,
+		Space:  n.Space,
+		Prefix: n.Prefix,
+	}
+	result.Attributes, result.order = n.copyAttributes()
+	if result.Attributes == nil {
+		result.Attributes = make(map[string]string)
 	}
-	maps.Copy(result.Attributes, n.Attributes)
 
 	if filter.HasText() {
 		if n.GetText() == filter.GetText() {
@@ -64,7 +106,7 @@ func (n *Node) SubtreeFilter(filter *Node) *Node {
 			for _, fc := range matches {
 				found := false
 				n.WalkNodes(func(nc *Node) {
-					if nc.SubtreeFilter(fc) != nil {
+					if nc.subtreeFilter(fc, data, selection) != nil {
 						found = true
 					}
 				})
@@ -80,7 +122,7 @@ func (n *Node) SubtreeFilter(filter *Node) *Node {
 					// Mixed: filter content based on filter children
 					for _, fc := range filters {
 						n.WalkNodes(func(nc *Node) {
-							if filtered := nc.SubtreeFilter(fc); filtered != nil {
+							if filtered := nc.subtreeFilter(fc, data, selection); filtered != nil {
 								result.AddChild(filtered)
 							}
 						})
@@ -97,7 +139,7 @@ func (n *Node) SubtreeFilter(filter *Node) *Node {
 			// Filter mode: recursively filter matching children
 			for _, fc := range filters {
 				n.WalkNodes(func(nc *Node) {
-					if filtered := nc.SubtreeFilter(fc); filtered != nil {
+					if filtered := nc.subtreeFilter(fc, data, selection); filtered != nil {
 						result.AddChild(filtered)
 					}
 				})
@@ -0,0 +1,101 @@
+package xmlnode
+
+import (
+	"iter"
+	"strings"
+)
+
+// WalkAction tells a traversal how to go on after visiting a node
+type WalkAction int
+
+const (
+	WalkContinue WalkAction = iota // Visit the children of the node
+	WalkSkip                       // Do not visit the children of the node
+	WalkStop                       // End the traversal
+)
+
+// Visit describes a node reached by a traversal
+type Visit struct {
+	Node   *Node
+	Parent *Node   // nil for the node the traversal started from
+	Depth  int     // 0 for the node the traversal started from
+	Path   []*Node // Nodes from the starting node down to Node; only valid during the callback
+}
+
+// PathString returns the path of the visit as slash separated qualified names, such as "/top/users"
+func (v Visit) PathString() string {
+	var path strings.Builder
+	for _, n := range v.Path {
+		path.WriteString("/")
+		path.WriteString(n.QualifiedName())
+	}
+	return path.String()
+}
+
+// WalkDepthFirst visits the node and its descendant elements in document order, each node before
+// its children
+func (n *Node) WalkDepthFirst(fn func(Visit) WalkAction) {
+	if n == nil || fn == nil {
+		return
+	}
+	n.walk(fn, nil)
+}
+
+// walk visits the node and its descendants, reporting whether the traversal was stopped
+func (n *Node) walk(fn func(Visit) WalkAction, path []*Node) bool {
+	path = append(path, n)
+	v := Visit{Node: n, Depth: len(path) - 1, Path: path}
+	if len(path) > 1 {
+		v.Parent = path[len(path)-2]
+	}
+	switch fn(v) {
+	case WalkStop:
+		return true
+	case WalkSkip:
+		return false
+	}
+	for _, child := range n.elements() {
+		if child != nil && child.walk(fn, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkBreadthFirst visits the node and its descendant elements level by level, each level in
+// document order
+func (n *Node) WalkBreadthFirst(fn func(Visit) WalkAction) {
+	if n == nil || fn == nil {
+		return
+	}
+	queue := []Visit{{Node: n, Path: []*Node{n}}}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		switch fn(v) {
+		case WalkStop:
+			return
+		case WalkSkip:
+			continue
+		}
+		for _, child := range v.Node.elements() {
+			if child == nil {
+				continue
+			}
+			path := append(v.Path[:len(v.Path):len(v.Path)], child)
+			queue = append(queue, Visit{Node: child, Parent: v.Node, Depth: v.Depth + 1, Path: path})
+		}
+	}
+}
+
+// Descendants returns an iterator over the descendant elements of the node in document order
+func (n *Node) Descendants() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		n.WalkDepthFirst(func(v Visit) WalkAction {
+			if v.Depth > 0 && !yield(v.Node) {
+				return WalkStop
+			}
+			return WalkContinue
+		})
+	}
+}
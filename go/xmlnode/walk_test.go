@@ -0,0 +1,91 @@
+package xmlnode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Trail walks a node and records each visit as its path, stopping or skipping at the given names
+func Trail(root *Node, breadthFirst bool, skip, stop string) []string {
+	var trail []string
+	fn := func(v Visit) WalkAction {
+		parent := ""
+		if v.Parent != nil {
+			parent = v.Parent.Name
+		}
+		if v.Depth != len(v.Path)-1 || v.Path[v.Depth] != v.Node || (v.Depth > 0 && v.Path[v.Depth-1].Name != parent) {
+			trail = append(trail, "inconsistent visit "+v.PathString())
+		}
+		trail = append(trail, fmt.Sprintf("%d %s", v.Depth, v.PathString()))
+		switch {
+		case v.Node.Name == stop:
+			return WalkStop
+		case v.Node.Name == skip:
+			return WalkSkip
+		}
+		return WalkContinue
+	}
+	if breadthFirst {
+		root.WalkBreadthFirst(fn)
+	} else {
+		root.WalkDepthFirst(fn)
+	}
+	return trail
+}
+
+const TREE = `<a><b><d/><e>text</e></b>mixed <c><f/></c><!-- comment --></a>`
+
+func TestWalk(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(TREE)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	tests := []struct {
+		name         string
+		breadthFirst bool
+		skip, stop   string
+		want         string
+	}{
+		{"depth first", false, "", "", "0 /a, 1 /a/b, 2 /a/b/d, 2 /a/b/e, 1 /a/c, 2 /a/c/f"},
+		{"depth first skip", false, "b", "", "0 /a, 1 /a/b, 1 /a/c, 2 /a/c/f"},
+		{"depth first stop", false, "", "e", "0 /a, 1 /a/b, 2 /a/b/d, 2 /a/b/e"},
+		{"breadth first", true, "", "", "0 /a, 1 /a/b, 1 /a/c, 2 /a/b/d, 2 /a/b/e, 2 /a/c/f"},
+		{"breadth first skip", true, "b", "", "0 /a, 1 /a/b, 1 /a/c, 2 /a/c/f"},
+		{"breadth first stop", true, "", "c", "0 /a, 1 /a/b, 1 /a/c"},
+	}
+	for _, test := range tests {
+		got := strings.Join(Trail(root, test.breadthFirst, test.skip, test.stop), ", ")
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(DATASTORE)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	var names []string
+	for node := range root.Descendants() {
+		if node.Name == "name" {
+			names = append(names, node.GetText())
+		}
+	}
+	if strings.Join(names, ",") != "root,fred,barney" {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	// Breaking out of the loop ends the traversal
+	count := 0
+	for node := range root.Descendants() {
+		count++
+		if node.Name == "user" {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("visited %d nodes before the first user", count)
+	}
+}
@@ -0,0 +1,711 @@
+package xmlnode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrXPathSyntax = errors.New("xpath syntax error")
+	ErrXPathType   = errors.New("xpath expression is not a node-set")
+)
+
+// XPath is a compiled XPath 1.0 expression. Prefixes in name tests are resolved with the
+// namespaces given to CompileXPath; as an extension, an entry for the empty prefix applies to
+// unprefixed element names, which otherwise select elements in no namespace. Variable references
+// are not supported and the namespace axis selects nothing.
+type XPath struct {
+	source string
+	root   expr
+}
+
+// XPathNode is a node of an XPath node-set
+type XPathNode struct {
+	Type  NodeType // TypeRoot, TypeElement, TypeAttribute, TypeText, TypeComment or TypeProcInst
+	Node  *Node    // The element, or the element holding the attribute, text, comment or instruction
+	Name  string   // Attribute key or instruction target
+	Value string   // String-value
+}
+
+// CompileXPath parses an XPath 1.0 expression, resolving prefixes with namespaces, a map from
+// prefix to namespace URI that may be nil
+func CompileXPath(source string, namespaces map[string]string) (*XPath, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, source: source, namespaces: namespaces}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &XPath{source: source, root: root}, nil
+}
+
+func (x *XPath) String() string {
+	return x.source
+}
+
+// Evaluate evaluates the expression with the node as context node, the root node being its parent.
+// The result is a float64, string, bool or []XPathNode in document order.
+func (x *XPath) Evaluate(context *Node) (any, error) {
+	t := build(context)
+	value, err := x.root.evaluate(&evaluation{tree: t, node: 1, position: 1, size: 1})
+	if err != nil {
+		return nil, err
+	}
+	if set, ok := value.(nodeSet); ok {
+		result := make([]XPathNode, len(set))
+		for i, index := range set {
+			result[i] = t.public(index)
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+// Select returns the elements of the node-set the expression selects from the context node
+func (x *XPath) Select(context *Node) ([]*Node, error) {
+	t := build(context)
+	set, err := x.selectSet(t)
+	if err != nil {
+		return nil, err
+	}
+	var result []*Node
+	for _, index := range set {
+		if t.nodes[index].kind == TypeElement {
+			result = append(result, t.nodes[index].node)
+		}
+	}
+	return result, nil
+}
+
+func (x *XPath) selectSet(t *tree) (nodeSet, error) {
+	value, err := x.root.evaluate(&evaluation{tree: t, node: 1, position: 1, size: 1})
+	if err != nil {
+		return nil, err
+	}
+	set, ok := value.(nodeSet)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrXPathType, x.source)
+	}
+	return set, nil
+}
+
+// XPathFilter applies a NETCONF XPath filter to the node: the result holds the selected nodes and
+// their ancestors. Selected elements keep their whole subtree, and an element holding a selected
+// attribute, text, comment or instruction is kept whole. It returns nil if nothing is selected.
+func (n *Node) XPathFilter(x *XPath) (*Node, error) {
+	if n == nil || x == nil {
+		return nil, nil
+	}
+	t := build(n)
+	set, err := x.selectSet(t)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		whole = make([]bool, len(t.nodes))
+		keep  = make([]bool, len(t.nodes))
+	)
+	for _, index := range set {
+		switch t.nodes[index].kind {
+		case TypeRoot:
+			whole[1] = true
+		case TypeElement:
+			whole[index] = true
+		default:
+			whole[t.nodes[index].parent] = true
+		}
+	}
+	for index := range whole {
+		if whole[index] {
+			for p := t.nodes[index].parent; p > 0 && !keep[p]; p = t.nodes[p].parent {
+				keep[p] = true
+			}
+		}
+	}
+	if !whole[1] && !keep[1] {
+		return nil, nil
+	}
+	return t.prune(1, whole, keep), nil
+}
+
+// prune copies the element at index with the children that are kept
+func (t *tree) prune(index int, whole, keep []bool) *Node {
+	n := t.nodes[index].node
+	result := &Node{Name: n.Name, Space: n.Space, Prefix: n.Prefix}
+	result.Attributes, result.order = n.copyAttributes()
+	if whole[index] {
+		result.Content = n.Content
+		return result
+	}
+	for _, child := range t.nodes[index].children {
+		if whole[child] || keep[child] {
+			result.AddChild(t.prune(child, whole, keep))
+		}
+	}
+	return result
+}
+
+const (
+	tokenEOF = iota
+	tokenName
+	tokenNumber
+	tokenLiteral
+	tokenVariable
+	tokenOperator
+)
+
+type token struct {
+	kind   int
+	text   string
+	offset int
+}
+
+// Two character operators are matched before single characters
+var operators = []string{"//", "..", "::", "!=", "<=", ">=", "/", "(", ")", "[", "]", ".", "@", ",", "|", "+", "-", "=", "<", ">", "*"}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isNameChar(r rune) bool {
+	return isNameStart(r) || r == '-' || r == '.' || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// scanName returns the end of the NCName starting at i, or i if there is none
+func scanName(source string, i int) int {
+	r, size := utf8.DecodeRuneInString(source[i:])
+	if i >= len(source) || !isNameStart(r) {
+		return i
+	}
+	for i = i + size; i < len(source); i = i + size {
+		r, size = utf8.DecodeRuneInString(source[i:])
+		if !isNameChar(r) {
+			break
+		}
+	}
+	return i
+}
+
+// lex splits an expression into tokens. Whether a name is an operator, function, axis or name test
+// and whether "*" multiplies is left to the parser, which knows what it expects.
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(source[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated literal at offset %d in %q", ErrXPathSyntax, i, source)
+			}
+			tokens = append(tokens, token{kind: tokenLiteral, text: source[i+1 : i+1+end], offset: i})
+			i = i + end + 2
+		case isDigit(c) || c == '.' && i+1 < len(source) && isDigit(source[i+1]):
+			j := i
+			for j < len(source) && isDigit(source[j]) {
+				j++
+			}
+			if j < len(source) && source[j] == '.' {
+				j++
+				for j < len(source) && isDigit(source[j]) {
+					j++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[i:j], offset: i})
+			i = j
+		case c == '$':
+			j := scanName(source, i+1)
+			if j < len(source) && source[j] == ':' {
+				j = scanName(source, j+1)
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("%w: missing variable name at offset %d in %q", ErrXPathSyntax, i, source)
+			}
+			tokens = append(tokens, token{kind: tokenVariable, text: source[i+1 : j], offset: i})
+			i = j
+		default:
+			if j := scanName(source, i); j > i {
+				// A single colon joins a prefix to a local name or "*"; "::" follows an axis name
+				if j+1 < len(source) && source[j] == ':' && source[j+1] != ':' {
+					if source[j+1] == '*' {
+						j = j + 2
+					} else if k := scanName(source, j+1); k > j+1 {
+						j = k
+					}
+				}
+				tokens = append(tokens, token{kind: tokenName, text: source[i:j], offset: i})
+				i = j
+				continue
+			}
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, offset: i})
+					i = i + len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: unexpected character %q at offset %d in %q", ErrXPathSyntax, c, i, source)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, offset: len(source)}), nil
+}
+
+type axis int
+
+const (
+	axisChild axis = iota
+	axisAncestor
+	axisAncestorOrSelf
+	axisAttribute
+	axisDescendant
+	axisDescendantOrSelf
+	axisFollowing
+	axisFollowingSibling
+	axisNamespace
+	axisParent
+	axisPreceding
+	axisPrecedingSibling
+	axisSelf
+)
+
+var axes = map[string]axis{
+	"child":              axisChild,
+	"ancestor":           axisAncestor,
+	"ancestor-or-self":   axisAncestorOrSelf,
+	"attribute":          axisAttribute,
+	"descendant":         axisDescendant,
+	"descendant-or-self": axisDescendantOrSelf,
+	"following":          axisFollowing,
+	"following-sibling":  axisFollowingSibling,
+	"namespace":          axisNamespace,
+	"parent":             axisParent,
+	"preceding":          axisPreceding,
+	"preceding-sibling":  axisPrecedingSibling,
+	"self":               axisSelf,
+}
+
+const (
+	testName = iota
+	testNode
+	testText
+	testComment
+	testProcInst
+)
+
+var nodeTypes = map[string]int{
+	"node":                   testNode,
+	"text":                   testText,
+	"comment":                testComment,
+	"processing-instruction": testProcInst,
+}
+
+// nodeTest selects nodes of an axis by kind or by name
+type nodeTest struct {
+	kind   int
+	space  string // Namespace URI of a name test
+	local  string // Local name of a name test, "*" for any
+	any    bool   // A "*" name test, matching any namespace
+	target string // Processing instruction target, empty for any
+}
+
+type step struct {
+	axis       axis
+	test       nodeTest
+	predicates []expr
+}
+
+type expr interface {
+	evaluate(e *evaluation) (any, error)
+}
+
+type (
+	binary struct {
+		op          string
+		left, right expr
+	}
+	negate struct {
+		operand expr
+	}
+	union struct {
+		left, right expr
+	}
+	literal string
+	number  float64
+	call    struct {
+		name      string
+		function  function
+		arguments []expr
+	}
+	filter struct {
+		primary    expr
+		predicates []expr
+	}
+	path struct {
+		filter   expr // Expression the steps apply to, nil for a location path
+		absolute bool
+		steps    []step
+	}
+)
+
+type parser struct {
+	tokens     []token
+	pos        int
+	source     string
+	namespaces map[string]string
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d in %q", ErrXPathSyntax, fmt.Sprintf(format, args...), t.offset, p.source)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(k int) token {
+	if p.pos+k < len(p.tokens) {
+		return p.tokens[p.pos+k]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(text string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.text == text
+}
+
+// isOperatorName reports whether the next token is one of the operators spelled as names, which
+// can only be operators where the parser expects one
+func (p *parser) isOperatorName(text string) bool {
+	t := p.peek()
+	return t.kind == tokenName && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	if t := p.next(); t.kind != tokenOperator || t.text != text {
+		return p.errorf(t, "expected %q", text)
+	}
+	return nil
+}
+
+func (p *parser) parseExpr() (expr, error) {
+	return p.parseBinary(0)
+}
+
+// levels lists the binary operators from the loosest binding to the tightest
+var levels = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+func (p *parser) parseBinary(level int) (expr, error) {
+	if level == len(levels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range levels[level] {
+			if p.isOperator(candidate) || p.isOperatorName(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isOperator("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negate{operand: operand}, nil
+	}
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("|") {
+		p.next()
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		left = &union{left: left, right: right}
+	}
+	return left, nil
+}
+
+// startsFilter reports whether the next tokens begin a filter expression rather than a location path
+func (p *parser) startsFilter() bool {
+	t := p.peek()
+	switch t.kind {
+	case tokenLiteral, tokenNumber, tokenVariable:
+		return true
+	case tokenOperator:
+		return t.text == "("
+	case tokenName:
+		next := p.peekAt(1)
+		_, nodeType := nodeTypes[t.text]
+		return next.kind == tokenOperator && next.text == "(" && !nodeType
+	}
+	return false
+}
+
+// startsStep reports whether the next token begins a location step
+func (p *parser) startsStep() bool {
+	t := p.peek()
+	switch t.kind {
+	case tokenName:
+		return true
+	case tokenOperator:
+		return t.text == "*" || t.text == "." || t.text == ".." || t.text == "@"
+	}
+	return false
+}
+
+func (p *parser) parsePath() (expr, error) {
+	if !p.startsFilter() {
+		return p.parseLocationPath()
+	}
+	primary, err := p.parseFilter()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator("/") && !p.isOperator("//") {
+		return primary, nil
+	}
+	result := &path{filter: primary}
+	if err := p.parseSteps(result, true); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *parser) parseLocationPath() (expr, error) {
+	result := new(path)
+	if p.isOperator("/") {
+		p.next()
+		result.absolute = true
+		if !p.startsStep() {
+			return result, nil
+		}
+	} else if p.isOperator("//") {
+		result.absolute = true
+		if err := p.parseSteps(result, true); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if err := p.parseSteps(result, false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// descendants is the step "//" abbreviates
+var descendants = step{axis: axisDescendantOrSelf, test: nodeTest{kind: testNode}}
+
+// parseSteps parses steps joined by "/" and "//", starting with a separator when separated is set
+func (p *parser) parseSteps(result *path, separated bool) error {
+	for {
+		if separated {
+			if p.isOperator("//") {
+				result.steps = append(result.steps, descendants)
+			} else if !p.isOperator("/") {
+				return nil
+			}
+			p.next()
+		}
+		s, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		result.steps = append(result.steps, s)
+		separated = true
+	}
+}
+
+func (p *parser) parseStep() (step, error) {
+	if p.isOperator(".") {
+		p.next()
+		return step{axis: axisSelf, test: nodeTest{kind: testNode}}, nil
+	}
+	if p.isOperator("..") {
+		p.next()
+		return step{axis: axisParent, test: nodeTest{kind: testNode}}, nil
+	}
+	s := step{axis: axisChild}
+	if p.isOperator("@") {
+		p.next()
+		s.axis = axisAttribute
+	} else if t, next := p.peek(), p.peekAt(1); t.kind == tokenName && next.kind == tokenOperator && next.text == "::" {
+		a, ok := axes[t.text]
+		if !ok {
+			return s, p.errorf(t, "unknown axis %q", t.text)
+		}
+		s.axis = a
+		p.pos = p.pos + 2
+	}
+	test, err := p.parseNodeTest(s.axis)
+	if err != nil {
+		return s, err
+	}
+	s.test = test
+	s.predicates, err = p.parsePredicates()
+	return s, err
+}
+
+// parseNodeTest reads a node test for the axis. An unprefixed name only takes the default namespace
+// of the expression on axes whose principal node type is element, never for attributes.
+func (p *parser) parseNodeTest(a axis) (nodeTest, error) {
+	t := p.next()
+	if t.kind == tokenOperator && t.text == "*" {
+		return nodeTest{kind: testName, local: "*", any: true}, nil
+	}
+	if t.kind != tokenName {
+		return nodeTest{}, p.errorf(t, "expected a node test")
+	}
+	if kind, ok := nodeTypes[t.text]; ok && p.isOperator("(") {
+		p.next()
+		test := nodeTest{kind: kind}
+		if kind == testProcInst && p.peek().kind == tokenLiteral {
+			test.target = p.next().text
+		}
+		return test, p.expect(")")
+	}
+	prefix, local := "", t.text
+	if colon := strings.IndexByte(t.text, ':'); colon >= 0 {
+		prefix, local = t.text[:colon], t.text[colon+1:]
+	}
+	if prefix == "" && (a == axisAttribute || a == axisNamespace) {
+		return nodeTest{kind: testName, local: local}, nil
+	}
+	space, ok := p.namespaces[prefix]
+	if !ok && prefix != "" {
+		if prefix != "xml" {
+			return nodeTest{}, p.errorf(t, "undeclared prefix %q", prefix)
+		}
+		space = XMLNamespace
+	}
+	return nodeTest{kind: testName, space: space, local: local}, nil
+}
+
+func (p *parser) parsePredicates() ([]expr, error) {
+	var predicates []expr
+	for p.isOperator("[") {
+		p.next()
+		predicate, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+func (p *parser) parseFilter() (expr, error) {
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	predicates, err := p.parsePredicates()
+	if err != nil || len(predicates) == 0 {
+		return primary, err
+	}
+	return &filter{primary: primary, predicates: predicates}, nil
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLiteral:
+		return literal(t.text), nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+		return number(f), nil
+	case tokenVariable:
+		return nil, p.errorf(t, "variable $%s is not supported", t.text)
+	case tokenOperator:
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+	f, ok := functions[t.text]
+	if !ok {
+		return nil, p.errorf(t, "unknown function %q", t.text)
+	}
+	c := &call{name: t.text, function: f}
+	p.next()
+	for !p.isOperator(")") {
+		if len(c.arguments) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		argument, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.arguments = append(c.arguments, argument)
+	}
+	p.next()
+	if len(c.arguments) < f.min || (f.max >= 0 && len(c.arguments) > f.max) {
+		return nil, p.errorf(t, "wrong number of arguments to %s()", t.text)
+	}
+	return c, nil
+}
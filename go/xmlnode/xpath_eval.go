@@ -0,0 +1,555 @@
+package xmlnode
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// xnode is a node of the XPath data model. Attribute, text, comment and processing instruction
+// nodes refer to the element holding them.
+type xnode struct {
+	kind     NodeType
+	node     *Node
+	space    string // Namespace URI of an element or attribute
+	name     string // Local name of an element or attribute, target of a processing instruction
+	qname    string // Name as written, with its prefix
+	value    string // String-value of attribute, text, comment and processing instruction nodes
+	parent   int
+	children []int
+	attrs    []int
+	end      int // Index past the last descendant
+}
+
+// tree holds the nodes of a document in document order, so that a node set is a sorted list of
+// indices and the descendants of a node follow it
+type tree struct {
+	nodes []xnode
+}
+
+// build creates the tree of a node, placed under a root node at index 0
+func build(context *Node) *tree {
+	t := &tree{nodes: []xnode{{kind: TypeRoot, parent: -1}}}
+	if context != nil {
+		t.nodes[0].children = []int{t.element(context, 0, nil)}
+	}
+	t.nodes[0].end = len(t.nodes)
+	return t
+}
+
+func (t *tree) add(x xnode) int {
+	t.nodes = append(t.nodes, x)
+	return len(t.nodes) - 1
+}
+
+func (t *tree) element(n *Node, parent int, s scope) int {
+	s = n.declare(s)
+	index := t.add(xnode{kind: TypeElement, node: n, space: n.space(s), name: n.Name, qname: n.QualifiedName(), parent: parent})
+	for _, key := range n.AttributeKeys() {
+		if IsNamespaceDeclaration(key) {
+			continue
+		}
+		space, local := s.resolve(key)
+		attr := t.add(xnode{kind: TypeAttribute, node: n, space: space, name: local, qname: key, value: n.Attributes[key], parent: index})
+		t.nodes[attr].end = attr + 1
+		t.nodes[index].attrs = append(t.nodes[index].attrs, attr)
+	}
+
+	var items Mixed
+	switch content := n.Content.(type) {
+	case Children:
+		for _, child := range content {
+			items = append(items, child)
+		}
+	case Mixed:
+		items = content
+	case nil:
+	default:
+		items = Mixed{content}
+	}
+	// Adjacent text and CDATA sections form a single text node
+	var text strings.Builder
+	leaf := func(x xnode) {
+		x.node, x.parent = n, index
+		i := t.add(x)
+		t.nodes[i].end = i + 1
+		t.nodes[index].children = append(t.nodes[index].children, i)
+	}
+	flush := func() {
+		if text.Len() > 0 {
+			leaf(xnode{kind: TypeText, value: text.String()})
+			text.Reset()
+		}
+	}
+	for _, item := range items {
+		switch item := item.(type) {
+		case Text:
+			text.WriteString(string(item))
+		case CData:
+			text.WriteString(string(item))
+		case *Node:
+			flush()
+			if item != nil {
+				child := t.element(item, index, s)
+				t.nodes[index].children = append(t.nodes[index].children, child)
+			}
+		case Comment:
+			flush()
+			leaf(xnode{kind: TypeComment, value: string(item)})
+		case ProcInst:
+			flush()
+			leaf(xnode{kind: TypeProcInst, name: item.Target, qname: item.Target, value: item.Inst})
+		}
+	}
+	flush()
+	t.nodes[index].end = len(t.nodes)
+	return index
+}
+
+// stringValue returns the string-value of a node, the text it contains for the root and elements
+func (t *tree) stringValue(index int) string {
+	x := &t.nodes[index]
+	if x.kind != TypeRoot && x.kind != TypeElement {
+		return x.value
+	}
+	var text strings.Builder
+	for i := index + 1; i < x.end; i++ {
+		if t.nodes[i].kind == TypeText {
+			text.WriteString(t.nodes[i].value)
+		}
+	}
+	return text.String()
+}
+
+func (t *tree) public(index int) XPathNode {
+	x := &t.nodes[index]
+	result := XPathNode{Type: x.kind, Node: x.node, Value: t.stringValue(index)}
+	if x.kind == TypeAttribute || x.kind == TypeProcInst {
+		result.Name = x.qname
+	}
+	return result
+}
+
+// axis returns the nodes along an axis from a node, in axis order: reverse document order for the
+// ancestor and preceding axes
+func (t *tree) axis(a axis, index int) []int {
+	var (
+		x      = &t.nodes[index]
+		result []int
+	)
+	switch a {
+	case axisChild:
+		return x.children
+	case axisAttribute:
+		return x.attrs
+	case axisSelf:
+		return []int{index}
+	case axisParent:
+		if x.parent >= 0 {
+			return []int{x.parent}
+		}
+	case axisAncestor, axisAncestorOrSelf:
+		start := x.parent
+		if a == axisAncestorOrSelf {
+			start = index
+		}
+		for i := start; i >= 0; i = t.nodes[i].parent {
+			result = append(result, i)
+		}
+	case axisDescendant, axisDescendantOrSelf:
+		if a == axisDescendantOrSelf {
+			result = append(result, index)
+		}
+		for i := index + 1; i < x.end; i++ {
+			if t.nodes[i].kind != TypeAttribute {
+				result = append(result, i)
+			}
+		}
+	case axisFollowingSibling, axisPrecedingSibling:
+		if x.kind == TypeAttribute || x.parent < 0 {
+			return nil
+		}
+		siblings := t.nodes[x.parent].children
+		k := slices.Index(siblings, index)
+		if a == axisFollowingSibling {
+			return siblings[k+1:]
+		}
+		result = slices.Clone(siblings[:k])
+		slices.Reverse(result)
+	case axisFollowing:
+		for i := x.end; i < len(t.nodes); i++ {
+			if t.nodes[i].kind != TypeAttribute {
+				result = append(result, i)
+			}
+		}
+	case axisPreceding:
+		ancestor := x.parent
+		for i := index - 1; i >= 0; i-- {
+			if i == ancestor {
+				ancestor = t.nodes[i].parent
+			} else if t.nodes[i].kind != TypeAttribute {
+				result = append(result, i)
+			}
+		}
+	case axisNamespace:
+		// Namespace nodes are not modelled
+	}
+	return result
+}
+
+func (test *nodeTest) match(x *xnode, principal NodeType) bool {
+	switch test.kind {
+	case testNode:
+		return true
+	case testText:
+		return x.kind == TypeText
+	case testComment:
+		return x.kind == TypeComment
+	case testProcInst:
+		return x.kind == TypeProcInst && (test.target == "" || test.target == x.name)
+	}
+	if x.kind != principal {
+		return false
+	}
+	if test.any {
+		return true
+	}
+	return x.space == test.space && (test.local == "*" || test.local == x.name)
+}
+
+// nodeSet is a set of tree indices in document order
+type nodeSet []int
+
+// evaluation is the context an expression is evaluated in
+type evaluation struct {
+	tree     *tree
+	node     int
+	position int
+	size     int
+}
+
+func (s *step) apply(t *tree, input nodeSet) (nodeSet, error) {
+	principal := TypeElement
+	if s.axis == axisAttribute {
+		principal = TypeAttribute
+	}
+	var result nodeSet
+	for _, index := range input {
+		var nodes []int
+		for _, i := range t.axis(s.axis, index) {
+			if s.test.match(&t.nodes[i], principal) {
+				nodes = append(nodes, i)
+			}
+		}
+		nodes, err := applyPredicates(t, nodes, s.predicates)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nodes...)
+	}
+	slices.Sort(result)
+	return slices.Compact(result), nil
+}
+
+// applyPredicates filters nodes in order by each predicate in turn; a number selects the node at
+// that position, any other value is converted to a boolean
+func applyPredicates(t *tree, nodes []int, predicates []expr) ([]int, error) {
+	for _, predicate := range predicates {
+		var kept []int
+		for i, index := range nodes {
+			value, err := predicate.evaluate(&evaluation{tree: t, node: index, position: i + 1, size: len(nodes)})
+			if err != nil {
+				return nil, err
+			}
+			if f, ok := value.(float64); ok {
+				if f == float64(i+1) {
+					kept = append(kept, index)
+				}
+			} else if toBoolean(value) {
+				kept = append(kept, index)
+			}
+		}
+		nodes = kept
+	}
+	return nodes, nil
+}
+
+func typeError(what string) error {
+	return fmt.Errorf("%w: %s", ErrXPathType, what)
+}
+
+func (p *path) evaluate(e *evaluation) (any, error) {
+	set := nodeSet{e.node}
+	if p.filter != nil {
+		value, err := p.filter.evaluate(e)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if set, ok = value.(nodeSet); !ok {
+			return nil, typeError("path applied to a value")
+		}
+	} else if p.absolute {
+		set = nodeSet{0}
+	}
+	for i := range p.steps {
+		var err error
+		if set, err = p.steps[i].apply(e.tree, set); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+func (f *filter) evaluate(e *evaluation) (any, error) {
+	value, err := f.primary.evaluate(e)
+	if err != nil {
+		return nil, err
+	}
+	set, ok := value.(nodeSet)
+	if !ok {
+		return nil, typeError("predicate applied to a value")
+	}
+	nodes, err := applyPredicates(e.tree, set, f.predicates)
+	return nodeSet(nodes), err
+}
+
+func (u *union) evaluate(e *evaluation) (any, error) {
+	left, err := u.left.evaluate(e)
+	if err != nil {
+		return nil, err
+	}
+	right, err := u.right.evaluate(e)
+	if err != nil {
+		return nil, err
+	}
+	l, ok1 := left.(nodeSet)
+	r, ok2 := right.(nodeSet)
+	if !ok1 || !ok2 {
+		return nil, typeError("union of values")
+	}
+	result := append(slices.Clone(l), r...)
+	slices.Sort(result)
+	return slices.Compact(result), nil
+}
+
+func (n *negate) evaluate(e *evaluation) (any, error) {
+	value, err := n.operand.evaluate(e)
+	if err != nil {
+		return nil, err
+	}
+	return -toNumber(e.tree, value), nil
+}
+
+func (l literal) evaluate(e *evaluation) (any, error) {
+	return string(l), nil
+}
+
+func (n number) evaluate(e *evaluation) (any, error) {
+	return float64(n), nil
+}
+
+func (c *call) evaluate(e *evaluation) (any, error) {
+	arguments := make([]any, len(c.arguments))
+	for i, argument := range c.arguments {
+		value, err := argument.evaluate(e)
+		if err != nil {
+			return nil, err
+		}
+		arguments[i] = value
+	}
+	return c.function.call(e, arguments)
+}
+
+func (b *binary) evaluate(e *evaluation) (any, error) {
+	left, err := b.left.evaluate(e)
+	if err != nil {
+		return nil, err
+	}
+	// The right operand of "or" and "and" is only evaluated when it decides the result
+	switch b.op {
+	case "or":
+		if toBoolean(left) {
+			return true, nil
+		}
+	case "and":
+		if !toBoolean(left) {
+			return false, nil
+		}
+	}
+	right, err := b.right.evaluate(e)
+	if err != nil {
+		return nil, err
+	}
+	t := e.tree
+	switch b.op {
+	case "or", "and":
+		return toBoolean(right), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return compare(t, b.op, left, right), nil
+	case "+":
+		return toNumber(t, left) + toNumber(t, right), nil
+	case "-":
+		return toNumber(t, left) - toNumber(t, right), nil
+	case "*":
+		return toNumber(t, left) * toNumber(t, right), nil
+	case "div":
+		return toNumber(t, left) / toNumber(t, right), nil
+	case "mod":
+		return math.Mod(toNumber(t, left), toNumber(t, right)), nil
+	}
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrXPathSyntax, b.op)
+}
+
+// compare applies a comparison; with a node-set operand it holds if it holds for any of its nodes
+func compare(t *tree, op string, left, right any) bool {
+	if set, ok := left.(nodeSet); ok {
+		if _, ok := right.(bool); ok {
+			return compareValues(t, op, toBoolean(left), right)
+		}
+		for _, index := range set {
+			if compare(t, op, t.stringValue(index), right) {
+				return true
+			}
+		}
+		return false
+	}
+	if set, ok := right.(nodeSet); ok {
+		if _, ok := left.(bool); ok {
+			return compareValues(t, op, left, toBoolean(right))
+		}
+		for _, index := range set {
+			if compareValues(t, op, left, t.stringValue(index)) {
+				return true
+			}
+		}
+		return false
+	}
+	return compareValues(t, op, left, right)
+}
+
+// compareValues compares two values that are not node-sets. Equality compares as booleans, then
+// numbers, then strings, whichever either side is first; ordering always compares numbers.
+func compareValues(t *tree, op string, left, right any) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, lb := left.(bool)
+		_, rb := right.(bool)
+		_, lf := left.(float64)
+		_, rf := right.(float64)
+		switch {
+		case lb || rb:
+			equal = toBoolean(left) == toBoolean(right)
+		case lf || rf:
+			equal = toNumber(t, left) == toNumber(t, right)
+		default:
+			equal = toString(t, left) == toString(t, right)
+		}
+		return equal == (op == "=")
+	}
+	l, r := toNumber(t, left), toNumber(t, right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	}
+	return l >= r
+}
+
+func toBoolean(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case nodeSet:
+		return len(v) > 0
+	}
+	return false
+}
+
+func toString(t *tree, value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return formatNumber(v)
+	case nodeSet:
+		if len(v) > 0 {
+			return t.stringValue(v[0])
+		}
+	}
+	return ""
+}
+
+func toNumber(t *tree, value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return parseNumber(toString(t, value))
+}
+
+// formatNumber writes a number as XPath does: integers without a decimal point, other finite
+// numbers in decimal notation without an exponent
+func formatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// parseNumber accepts an optional minus sign and decimal digits with an optional point, surrounded
+// by whitespace; anything else is NaN
+func parseNumber(s string) float64 {
+	s = strings.Trim(s, " \t\r\n")
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || digits == "." {
+		return math.NaN()
+	}
+	point := false
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == '.' && !point {
+			point = true
+		} else if !isDigit(digits[i]) {
+			return math.NaN()
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+// round rounds half up, keeping negative zero for values from -0.5 to zero as XPath requires
+func round(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f == 0 {
+		return f
+	}
+	if f < 0 && f >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+	return math.Floor(f + 0.5)
+}
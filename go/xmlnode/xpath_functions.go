@@ -0,0 +1,244 @@
+package xmlnode
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// function is an XPath core library function taking min to max arguments, any number when max is
+// negative
+type function struct {
+	min, max int
+	call     func(e *evaluation, arguments []any) (any, error)
+}
+
+var functions = map[string]function{
+	// Node set functions
+	"last":          {0, 0, func(e *evaluation, _ []any) (any, error) { return float64(e.size), nil }},
+	"position":      {0, 0, func(e *evaluation, _ []any) (any, error) { return float64(e.position), nil }},
+	"count":         {1, 1, xpathCount},
+	"id":            {1, 1, xpathID},
+	"local-name":    {0, 1, xpathLocalName},
+	"namespace-uri": {0, 1, xpathNamespaceURI},
+	"name":          {0, 1, xpathQualifiedName},
+	// String functions
+	"string":           {0, 1, func(e *evaluation, a []any) (any, error) { return stringArgument(e, a), nil }},
+	"concat":           {2, -1, xpathConcat},
+	"starts-with":      {2, 2, xpathStartsWith},
+	"contains":         {2, 2, xpathContains},
+	"substring-before": {2, 2, xpathSubstringBefore},
+	"substring-after":  {2, 2, xpathSubstringAfter},
+	"substring":        {2, 3, xpathSubstring},
+	"string-length":    {0, 1, xpathStringLength},
+	"normalize-space":  {0, 1, xpathNormalizeSpace},
+	"translate":        {3, 3, xpathTranslate},
+	// Boolean functions
+	"boolean": {1, 1, func(e *evaluation, a []any) (any, error) { return toBoolean(a[0]), nil }},
+	"not":     {1, 1, func(e *evaluation, a []any) (any, error) { return !toBoolean(a[0]), nil }},
+	"true":    {0, 0, func(e *evaluation, _ []any) (any, error) { return true, nil }},
+	"false":   {0, 0, func(e *evaluation, _ []any) (any, error) { return false, nil }},
+	"lang":    {1, 1, xpathLang},
+	// Number functions
+	"number":  {0, 1, func(e *evaluation, a []any) (any, error) { return toNumber(e.tree, contextArgument(e, a)), nil }},
+	"sum":     {1, 1, xpathSum},
+	"floor":   {1, 1, func(e *evaluation, a []any) (any, error) { return math.Floor(toNumber(e.tree, a[0])), nil }},
+	"ceiling": {1, 1, func(e *evaluation, a []any) (any, error) { return math.Ceil(toNumber(e.tree, a[0])), nil }},
+	"round":   {1, 1, func(e *evaluation, a []any) (any, error) { return round(toNumber(e.tree, a[0])), nil }},
+}
+
+// contextArgument returns the optional argument, the context node when it is missing
+func contextArgument(e *evaluation, arguments []any) any {
+	if len(arguments) == 0 {
+		return nodeSet{e.node}
+	}
+	return arguments[0]
+}
+
+func stringArgument(e *evaluation, arguments []any) string {
+	return toString(e.tree, contextArgument(e, arguments))
+}
+
+func nodeSetArgument(value any, name string) (nodeSet, error) {
+	set, ok := value.(nodeSet)
+	if !ok {
+		return nil, typeError("argument to " + name + "()")
+	}
+	return set, nil
+}
+
+func xpathCount(e *evaluation, arguments []any) (any, error) {
+	set, err := nodeSetArgument(arguments[0], "count")
+	return float64(len(set)), err
+}
+
+func xpathSum(e *evaluation, arguments []any) (any, error) {
+	set, err := nodeSetArgument(arguments[0], "sum")
+	total := 0.0
+	for _, index := range set {
+		total = total + parseNumber(e.tree.stringValue(index))
+	}
+	return total, err
+}
+
+// xpathID selects the elements whose xml:id attribute is one of the whitespace separated tokens
+// of the argument, there being no DTD to declare other ID attributes
+func xpathID(e *evaluation, arguments []any) (any, error) {
+	var tokens []string
+	if set, ok := arguments[0].(nodeSet); ok {
+		for _, index := range set {
+			tokens = append(tokens, strings.Fields(e.tree.stringValue(index))...)
+		}
+	} else {
+		tokens = strings.Fields(toString(e.tree, arguments[0]))
+	}
+	var result nodeSet
+	for i := range e.tree.nodes {
+		x := &e.tree.nodes[i]
+		if x.kind == TypeAttribute && x.space == XMLNamespace && x.name == "id" && slices.Contains(tokens, x.value) {
+			result = append(result, x.parent)
+		}
+	}
+	return result, nil
+}
+
+// named returns the first node of the optional node-set argument, reporting whether there is one
+func named(e *evaluation, arguments []any, name string) (*xnode, error) {
+	set, err := nodeSetArgument(contextArgument(e, arguments), name)
+	if err != nil || len(set) == 0 {
+		return nil, err
+	}
+	return &e.tree.nodes[set[0]], nil
+}
+
+func xpathLocalName(e *evaluation, arguments []any) (any, error) {
+	x, err := named(e, arguments, "local-name")
+	if x == nil {
+		return "", err
+	}
+	return x.name, nil
+}
+
+func xpathNamespaceURI(e *evaluation, arguments []any) (any, error) {
+	x, err := named(e, arguments, "namespace-uri")
+	if x == nil {
+		return "", err
+	}
+	return x.space, nil
+}
+
+func xpathQualifiedName(e *evaluation, arguments []any) (any, error) {
+	x, err := named(e, arguments, "name")
+	if x == nil {
+		return "", err
+	}
+	return x.qname, nil
+}
+
+func xpathConcat(e *evaluation, arguments []any) (any, error) {
+	var result strings.Builder
+	for _, argument := range arguments {
+		result.WriteString(toString(e.tree, argument))
+	}
+	return result.String(), nil
+}
+
+func xpathStartsWith(e *evaluation, arguments []any) (any, error) {
+	return strings.HasPrefix(toString(e.tree, arguments[0]), toString(e.tree, arguments[1])), nil
+}
+
+func xpathContains(e *evaluation, arguments []any) (any, error) {
+	return strings.Contains(toString(e.tree, arguments[0]), toString(e.tree, arguments[1])), nil
+}
+
+func xpathSubstringBefore(e *evaluation, arguments []any) (any, error) {
+	before, _, found := strings.Cut(toString(e.tree, arguments[0]), toString(e.tree, arguments[1]))
+	if !found {
+		return "", nil
+	}
+	return before, nil
+}
+
+func xpathSubstringAfter(e *evaluation, arguments []any) (any, error) {
+	_, after, found := strings.Cut(toString(e.tree, arguments[0]), toString(e.tree, arguments[1]))
+	if !found {
+		return "", nil
+	}
+	return after, nil
+}
+
+// xpathSubstring keeps the characters whose position p, counting from one, satisfies
+// round(start) <= p < round(start) + round(length)
+func xpathSubstring(e *evaluation, arguments []any) (any, error) {
+	var (
+		s     = toString(e.tree, arguments[0])
+		first = round(toNumber(e.tree, arguments[1]))
+		last  = math.Inf(1)
+	)
+	if len(arguments) == 3 {
+		last = first + round(toNumber(e.tree, arguments[2]))
+	}
+	var result strings.Builder
+	position := 1
+	for _, r := range s {
+		if p := float64(position); p >= first && p < last {
+			result.WriteRune(r)
+		}
+		position++
+	}
+	return result.String(), nil
+}
+
+func xpathStringLength(e *evaluation, arguments []any) (any, error) {
+	return float64(utf8.RuneCountInString(stringArgument(e, arguments))), nil
+}
+
+func xpathNormalizeSpace(e *evaluation, arguments []any) (any, error) {
+	return strings.Join(strings.FieldsFunc(stringArgument(e, arguments), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	}), " "), nil
+}
+
+// xpathTranslate replaces each character of the string found in the second argument by the
+// character at the same position in the third, removing it when the third argument is shorter
+func xpathTranslate(e *evaluation, arguments []any) (any, error) {
+	var (
+		from    = []rune(toString(e.tree, arguments[1]))
+		to      = []rune(toString(e.tree, arguments[2]))
+		mapping = make(map[rune]rune, len(from))
+	)
+	for i := len(from) - 1; i >= 0; i-- {
+		// The first occurrence of a character decides its mapping
+		if i < len(to) {
+			mapping[from[i]] = to[i]
+		} else {
+			mapping[from[i]] = -1
+		}
+	}
+	var result strings.Builder
+	for _, r := range toString(e.tree, arguments[0]) {
+		if m, ok := mapping[r]; !ok {
+			result.WriteRune(r)
+		} else if m >= 0 {
+			result.WriteRune(m)
+		}
+	}
+	return result.String(), nil
+}
+
+// xpathLang reports whether the xml:lang attribute in scope names the language or one of its
+// sublanguages
+func xpathLang(e *evaluation, arguments []any) (any, error) {
+	want := strings.ToLower(toString(e.tree, arguments[0]))
+	for i := e.node; i >= 0; i = e.tree.nodes[i].parent {
+		for _, attr := range e.tree.nodes[i].attrs {
+			x := &e.tree.nodes[attr]
+			if x.space == XMLNamespace && x.name == "lang" {
+				have := strings.ToLower(x.value)
+				return have == want || strings.HasPrefix(have, want+"-"), nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,233 @@
+package xmlnode
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const LIBRARY = `
+<library xmlns="urn:lib" xmlns:x="urn:extra" xml:lang="en-GB">
+  <book id="b1" year="1999" x:rating="5"><title>Go</title><price>30</price></book>
+  <!-- note -->
+  <book id="b2" year="2005"><title>XML</title><price>12.5</price></book>
+  <book id="b3" year="2010" xml:id="third"><title>XPath <em>1.0</em> guide</title><price>7</price></book>
+  <?sort by-year?>
+</library>
+`
+
+// Render writes an XPath result for comparison: node-sets as one entry per node
+func Render(value any) string {
+	switch v := value.(type) {
+	case []XPathNode:
+		var parts []string
+		for _, node := range v {
+			switch node.Type {
+			case TypeRoot:
+				parts = append(parts, "/")
+			case TypeElement:
+				name := node.Node.Name
+				if id := node.Node.GetAttribute("id"); id != "" {
+					name = name + ":" + id
+				}
+				parts = append(parts, name)
+			case TypeAttribute:
+				parts = append(parts, "@"+node.Name+"="+node.Value)
+			case TypeText:
+				parts = append(parts, "text:"+node.Value)
+			case TypeComment:
+				parts = append(parts, "comment:"+node.Value)
+			case TypeProcInst:
+				parts = append(parts, "pi:"+node.Name)
+			}
+		}
+		return strings.Join(parts, " | ")
+	case float64:
+		return formatNumber(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return "?"
+}
+
+func TestXPathEvaluate(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(LIBRARY)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	namespaces := map[string]string{"l": "urn:lib", "x": "urn:extra"}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// Location paths and axes
+		{"/", "/"},
+		{"self::l:library", "library"},
+		{"/l:library/l:book", "book:b1 | book:b2 | book:b3"},
+		{"count(/library)", "0"},
+		{"count(//*)", "11"},
+		{"l:book[last()]/@id", "@id=b3"},
+		{"l:book/@x:rating", "@x:rating=5"},
+		{"//l:em/ancestor::*", "library | book:b3 | title"},
+		{"//l:em/ancestor::*[1]", "title"},
+		{"l:book[1]/following-sibling::l:book[1]/@id", "@id=b2"},
+		{"l:book[3]/preceding-sibling::l:book[1]/@id", "@id=b2"},
+		{"(l:book[3]/preceding-sibling::l:book)[1]/@id", "@id=b1"},
+		{"count(//l:book[2]/preceding::*)", "3"},
+		{"string(//l:book[2]/following::l:price)", "7"},
+		{"count(//l:title/descendant-or-self::node())", "9"},
+		{"//l:title/text()", "text:Go | text:XML | text:XPath  | text: guide"},
+		{"//comment()", "comment: note "},
+		{"processing-instruction('sort')", "pi:sort"},
+		{"processing-instruction('other')", ""},
+		{"child::*[self::l:book][2]/@id", "@id=b2"},
+		{"//l:title[contains(., 'Path')]/../@id", "@id=b3"},
+		{"id('third b9')/@id", "@id=b3"},
+		{"count(//l:book | //l:title | //l:book)", "6"},
+		{"count(namespace::*)", "0"},
+		// Predicates
+		{"l:book[@year > 2000]/@id", "@id=b2 | @id=b3"},
+		{"l:book[@id='b2' or @year=2010]/@id", "@id=b2 | @id=b3"},
+		{"//l:book[l:price < 20][1]/@id", "@id=b2"},
+		{"//l:book[position() = last() - 1]/@id", "@id=b2"},
+		{"l:book[lang('en-gb')]/@id", "@id=b1 | @id=b2 | @id=b3"},
+		// Node set, string and boolean functions
+		{"string(l:book[2]/l:title)", "XML"},
+		{"string(//l:book[3]/l:title)", "XPath 1.0 guide"},
+		{"sum(//l:price)", "49.5"},
+		{"name(l:book/@x:rating)", "x:rating"},
+		{"local-name(/*)", "library"},
+		{"namespace-uri(/*)", "urn:lib"},
+		{"lang('en')", "true"},
+		{"lang('fr')", "false"},
+		{"substring('12345', 1.5, 2.6)", "234"},
+		{"substring('12345', 0, 3)", "12"},
+		{"substring('12345', 0 div 0, 3)", ""},
+		{"substring('12345', -42, 1 div 0)", "12345"},
+		{"translate('bar', 'abc', 'ABC')", "BAr"},
+		{"translate('--aaa--', 'abc-', 'ABC')", "AAA"},
+		{"normalize-space('  a  b ')", "a b"},
+		{"substring-before('1999/04/01', '/')", "1999"},
+		{"substring-after('1999/04/01', '/')", "04/01"},
+		{"concat('a', 1, true())", "a1true"},
+		{"string-length('héllo')", "5"},
+		{"starts-with('xpath', 'xp')", "true"},
+		// Numbers and comparisons
+		{"1 + 2 * 3 - 4 div 8 mod 3", "6.5"},
+		{"- - 2", "2"},
+		{"7 mod -3", "1"},
+		{"round(-0.5)", "0"},
+		{"round(2.5)", "3"},
+		{"floor(-1.5)", "-2"},
+		{"ceiling(1.2)", "2"},
+		{"1 div 0", "Infinity"},
+		{"0 div 0 = 0 div 0", "false"},
+		{"number('  12 ')", "12"},
+		{"number('1e3')", "NaN"},
+		{"0.1 + 0.2 > 0.3", "true"},
+		{"true() = 'x'", "true"},
+		{"//l:price = 7", "true"},
+		{"//l:price != 7", "true"},
+		{"not(//l:price = 8)", "true"},
+		{"//l:price > 29", "true"},
+		{"l:book/@year = l:book/@year", "true"},
+		{"//l:nothing = false()", "true"},
+	}
+	for _, test := range tests {
+		x, err := CompileXPath(test.expr, namespaces)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		value, err := x.Evaluate(root)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if got := Render(value); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestXPathDefaultNamespace(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(LIBRARY)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	x, err := CompileXPath("/library/book[price > 10]/title", map[string]string{"": "urn:lib"})
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	nodes, err := x.Select(root)
+	if err != nil || len(nodes) != 2 || nodes[0].GetText() != "Go" || nodes[1].GetText() != "XML" {
+		t.Errorf("unexpected selection %v, err: %v", nodes, err)
+	}
+
+	// Unprefixed attribute names stay in no namespace
+	x, err = CompileXPath("/library/book[@id='b1']/title", map[string]string{"": "urn:lib"})
+	if err != nil {
+		t.Fatal("Error: ", err)
+	}
+	nodes, err = x.Select(root)
+	if err != nil || len(nodes) != 1 || nodes[0].GetText() != "Go" {
+		t.Errorf("unexpected selection %v, err: %v", nodes, err)
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	for _, expr := range []string{"", "l:book[", "foo(1)", "$x", "unknown::a", "u:a", "'abc", "count()", "1 +", "@", "a b"} {
+		if _, err := CompileXPath(expr, map[string]string{"l": "urn:lib"}); !errors.Is(err, ErrXPathSyntax) {
+			t.Errorf("%q: expected ErrXPathSyntax, got %v", expr, err)
+		}
+	}
+	root := &Node{Name: "a"}
+	for _, expr := range []string{"count(1)", "'a'/b", "1 | a", "(1)[1]"} {
+		x, err := CompileXPath(expr, nil)
+		if err != nil {
+			t.Errorf("%q: %v", expr, err)
+			continue
+		}
+		if _, err := x.Evaluate(root); !errors.Is(err, ErrXPathType) {
+			t.Errorf("%q: expected ErrXPathType, got %v", expr, err)
+		}
+	}
+	x, _ := CompileXPath("1 + 1", nil)
+	if _, err := x.Select(root); !errors.Is(err, ErrXPathType) {
+		t.Errorf("expected ErrXPathType, got %v", err)
+	}
+}
+
+func TestXPathFilter(t *testing.T) {
+	root := new(Node)
+	if err := root.FromXML([]byte(DATASTORE)); err != nil {
+		t.Fatal("Error: ", err)
+	}
+	namespaces := map[string]string{"t": "http://example.com/schema/1.2/config"}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"/t:top/t:users/t:user[t:name='fred']/t:company-info/t:id",
+			`<top xmlns="http://example.com/schema/1.2/config"><users><user><company-info><id>2</id></company-info></user></users></top>`},
+		{"//t:user[t:type='admin']/t:name/text()",
+			`<top xmlns="http://example.com/schema/1.2/config"><users><user><name>fred</name></user><user><name>barney</name></user></users></top>`},
+		{"//t:user[t:name='nobody']", ""},
+	}
+	for _, test := range tests {
+		x, err := CompileXPath(test.expr, namespaces)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		filtered, err := root.XPathFilter(x)
+		if err != nil {
+			t.Fatalf("%s: %v", test.expr, err)
+		}
+		if got, _ := filtered.ToXML(false); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.expr, got, test.want)
+		}
+	}
+}